@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// maxAdminShutdownDelay caps ?delay= on /admin/shutdown.
+const maxAdminShutdownDelay = 10 * time.Minute
+
+// shutdownRequests hands a shutdown asked for over HTTP to main, which then
+// runs the same drain and graceful shutdown as for SIGTERM. The value is the
+// reason to log.
+var shutdownRequests = make(chan string, 1)
+
+// shutdownScheduled is set once /admin/shutdown has accepted a request.
+var shutdownScheduled atomic.Bool
+
+// ShutdownPlan is the body /admin/shutdown answers 202 with.
+type ShutdownPlan struct {
+	Reason     string    `json:"reason"`
+	Delay      string    `json:"delay"`
+	ShutdownAt time.Time `json:"shutdown_at"`
+}
+
+// newAdminShutdownHandler lets chaos tests restart one pod gracefully over
+// HTTP. The caller must send token in X-Admin-Token; ?delay= (a Go duration,
+// at most maxAdminShutdownDelay) postpones the shutdown. Only the first
+// accepted request counts.
+func newAdminShutdownHandler(token string) http.HandlerFunc {
+	want := sha256.Sum256([]byte(token))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordRequest(r)
+		log := requestLogger(r)
+		if !requireMethod(w, r, http.MethodPost) {
+			return
+		}
+
+		// Hash both sides so the comparison doesn't leak the length
+		got := sha256.Sum256([]byte(r.Header.Get("X-Admin-Token")))
+		if subtle.ConstantTimeCompare(want[:], got[:]) != 1 {
+			log.Warn("🔐 Admin shutdown refused - bad token", "remote_ip", clientIP(r))
+			writeError(w, http.StatusUnauthorized, "Unauthorized", "missing or wrong X-Admin-Token")
+			return
+		}
+
+		var delay time.Duration
+		if raw := r.URL.Query().Get("delay"); raw != "" {
+			d, err := time.ParseDuration(raw)
+			if err != nil || d < 0 || d > maxAdminShutdownDelay {
+				writeError(w, http.StatusBadRequest, "Invalid delay",
+					fmt.Sprintf("delay must be a duration between 0s and %s", maxAdminShutdownDelay))
+				return
+			}
+			delay = d
+		}
+
+		if !shutdownScheduled.CompareAndSwap(false, true) {
+			writeError(w, http.StatusConflict, "Shutdown already scheduled", "this instance is already going down")
+			return
+		}
+
+		plan := ShutdownPlan{
+			Reason:     "admin request from " + clientIP(r),
+			Delay:      delay.String(),
+			ShutdownAt: time.Now().Add(delay),
+		}
+		log.Warn("[SHUTDOWN] 🧨 Admin shutdown scheduled - it's giving chaos monkey",
+			"reason", plan.Reason, "delay", plan.Delay, "shutdown_at", plan.ShutdownAt.Format(time.RFC3339))
+		time.AfterFunc(delay, func() { shutdownRequests <- plan.Reason })
+
+		w.Header().Set("Content-Type", mimeJSON)
+		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(plan); err != nil {
+			log.Error("😱 Failed to encode shutdown plan JSON", "error", err)
+		}
+	}
+}
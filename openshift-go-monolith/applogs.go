@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultLogRingSize is how many log lines are kept when
+// LOG_RING_BUFFER_SIZE is unset.
+const defaultLogRingSize = 1000
+
+// logSubscriberBuffer is how many lines a stream may fall behind before it
+// starts missing them.
+const logSubscriberBuffer = 64
+
+// logRing keeps the last lines the logger wrote and fans new ones out to
+// live subscribers. It is an io.Writer so it can sit beside stdout; slog
+// writes one whole line per call.
+type logRing struct {
+	mu     sync.Mutex
+	lines  []string
+	next   int
+	full   bool
+	subs   map[chan string]struct{}
+	closed bool
+}
+
+// appLogs holds the application's own log output for /api/applogs.
+var appLogs = newLogRing(defaultLogRingSize)
+
+func newLogRing(size int) *logRing {
+	return &logRing{lines: make([]string, size), subs: make(map[chan string]struct{})}
+}
+
+func (l *logRing) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+		s := string(line)
+		l.lines[l.next] = s
+		l.next = (l.next + 1) % len(l.lines)
+		if l.next == 0 {
+			l.full = true
+		}
+		// A slow reader drops lines rather than stalling every log call
+		for ch := range l.subs {
+			select {
+			case ch <- s:
+			default:
+			}
+		}
+	}
+	return len(p), nil
+}
+
+// tail returns up to n of the most recent lines, oldest first.
+func (l *logRing) tail(n int) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := l.next
+	if l.full {
+		count = len(l.lines)
+	}
+	n = min(n, count)
+	out := make([]string, 0, n)
+	for i := l.next - n; i < l.next; i++ {
+		out = append(out, l.lines[(i+len(l.lines))%len(l.lines)])
+	}
+	return out
+}
+
+// subscribe returns a channel that receives every line written from now on
+// and a func to stop. The channel is closed once the ring is closed.
+func (l *logRing) subscribe() (<-chan string, func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ch := make(chan string, logSubscriberBuffer)
+	if l.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	l.subs[ch] = struct{}{}
+	return ch, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if _, ok := l.subs[ch]; ok {
+			delete(l.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// close ends every stream so server shutdown isn't held up by them. It is
+// safe to call more than once.
+func (l *logRing) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closed = true
+	for ch := range l.subs {
+		delete(l.subs, ch)
+		close(ch)
+	}
+}
+
+// logRingSize reads LOG_RING_BUFFER_SIZE. It runs before the logger exists,
+// so a bad value is reported back rather than logged.
+func logRingSize() (size int, invalid string) {
+	raw := lookupSetting("LOG_RING_BUFFER_SIZE")
+	if raw == "" {
+		return defaultLogRingSize, ""
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		return defaultLogRingSize, raw
+	}
+	return n, ""
+}
+
+// appLogsHandler serves the last ?lines=N (default 100) lines of the
+// application's own log as a JSON array, oldest first.
+func appLogsHandler(w http.ResponseWriter, r *http.Request) {
+	recordRequest(r)
+	log := requestLogger(r)
+	log.Debug("📜 App logs request received", "method", r.Method, "path", r.URL.Path, "remote_addr", clientIP(r))
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	n := 100
+	if raw := r.URL.Query().Get("lines"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			writeError(w, http.StatusBadRequest, "Invalid lines", "lines must be a positive integer")
+			return
+		}
+		n = v
+	}
+
+	w.Header().Set("Content-Type", mimeJSON)
+	if err := json.NewEncoder(w).Encode(appLogs.tail(n)); err != nil {
+		log.Error("😱 Failed to encode app logs JSON", "error", err)
+	}
+}
+
+// appLogsStreamHandler pushes each new log line to the client as it is
+// written, one per line over a chunked response, until the client goes away
+// or the server shuts down. Lines are dropped if the client can't keep up.
+func appLogsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	recordRequest(r)
+	log := requestLogger(r)
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// The stream outlives HTTP_WRITE_TIMEOUT by design
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Warn("⚠️ Can't lift write deadline for log stream", "error", err)
+	}
+
+	lines, stop := appLogs.subscribe()
+	defer stop()
+	log.Info("📡 App log stream opened - tuning in", "remote_addr", clientIP(r))
+
+	w.Header().Set("Content-Type", mimeText+"; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		log.Error("💥 Log stream can't flush - giving up", "error", err)
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			log.Info("👋 App log stream closed by client", "remote_addr", clientIP(r))
+			return
+		case line, ok := <-lines:
+			if !ok {
+				return
+			}
+			if _, err := w.Write([]byte(line + "\n")); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// basicAuthMiddleware guards a handler with HTTP Basic auth against
+// API_AUTH_USER and either API_AUTH_PASS_HASH (bcrypt, preferred) or
+// API_AUTH_PASS. With no credentials configured it lets everything through
+// and says so at startup; validateAuthConfig catches half-set credentials.
+func basicAuthMiddleware(realm string) func(http.Handler) http.Handler {
+	user := os.Getenv("API_AUTH_USER")
+	pass := os.Getenv("API_AUTH_PASS")
+	hash := os.Getenv("API_AUTH_PASS_HASH")
+
+	if user == "" || (pass == "" && hash == "") {
+		logger.Warn("[CONFIG] ⚠️ API_AUTH_USER/API_AUTH_PASS not set - write and delete endpoints are unauthenticated", "realm", realm)
+		return func(next http.Handler) http.Handler { return next }
+	}
+	logger.Info("[CONFIG] 🔒 Basic auth enabled", "realm", realm, "user", user, "bcrypt", hash != "")
+
+	checkPassword := func(given string) bool {
+		if hash != "" {
+			return bcrypt.CompareHashAndPassword([]byte(hash), []byte(given)) == nil
+		}
+		// Hash both sides so the comparison doesn't leak the length
+		want, got := sha256.Sum256([]byte(pass)), sha256.Sum256([]byte(given))
+		return subtle.ConstantTimeCompare(want[:], got[:]) == 1
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			givenUser, givenPass, ok := r.BasicAuth()
+			userOK := subtle.ConstantTimeCompare([]byte(givenUser), []byte(user)) == 1
+			if !ok || !checkPassword(givenPass) || !userOK {
+				requestLogger(r).Warn("🔐 Unauthorized request - who even are you?",
+					"method", r.Method, "path", r.URL.Path, "remote_ip", clientIP(r), "credentials_sent", ok)
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", realm))
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// validateAuthConfig reports credentials that are only partly configured,
+// which would otherwise silently leave the endpoints open.
+func validateAuthConfig() []string {
+	user := os.Getenv("API_AUTH_USER")
+	pass := os.Getenv("API_AUTH_PASS")
+	hash := os.Getenv("API_AUTH_PASS_HASH")
+
+	var problems []string
+	switch {
+	case user != "" && pass == "" && hash == "":
+		problems = append(problems, "API_AUTH_USER is set but neither API_AUTH_PASS nor API_AUTH_PASS_HASH is")
+	case user == "" && (pass != "" || hash != ""):
+		problems = append(problems, "API_AUTH_PASS or API_AUTH_PASS_HASH is set without API_AUTH_USER")
+	}
+	if hash != "" {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			problems = append(problems, fmt.Sprintf("API_AUTH_PASS_HASH is not a valid bcrypt hash: %v", err))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// maxBatchEntries caps how many files one /api/write/batch call may create.
+const maxBatchEntries = 50
+
+// BatchWriteRequest is the JSON body for /api/write/batch.
+type BatchWriteRequest struct {
+	Entries []WritePayload `json:"entries"`
+}
+
+// BatchWriteResult reports what happened to one batch entry.
+type BatchWriteResult struct {
+	Filename  string `json:"filename,omitempty"`
+	Sequence  int64  `json:"sequence,omitempty"`
+	SizeBytes int    `json:"size_bytes"`
+	Error     string `json:"error,omitempty"`
+}
+
+// newBatchWriteHandler writes each entry of a batch as its own log file,
+// named by its write sequence number so files created in the same second,
+// by this batch or any other write, don't overwrite each other. The answer is 200 when every entry landed and 207
+// Multi-Status when any of them failed.
+func newBatchWriteHandler(current settingsSource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordRequest(r)
+		log := requestLogger(r)
+		cfg := current()
+
+		log.Info("📚 Batch write request received", "method", r.Method, "path", r.URL.Path, "remote_addr", clientIP(r))
+		if !requireMethod(w, r, http.MethodPost) {
+			return
+		}
+		if cfg.Features[featureDisableWrite] {
+			log.Warn("🚫 Batch write rejected - disabled by feature flag", "feature", featureDisableWrite)
+			writeFeatureDisabled(w, featureDisableWrite, "Writes are disabled on this instance")
+			return
+		}
+
+		var req BatchWriteRequest
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchEntries*maxPayloadBytes)).Decode(&req)
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxErr):
+			atomic.AddInt64(&oversizedBodies, 1)
+			log.Warn("🐘 Batch payload too large", "limit_bytes", maxErr.Limit,
+				"content_length", r.ContentLength, "remote_addr", clientIP(r))
+			writeError(w, http.StatusRequestEntityTooLarge, "Payload too large",
+				fmt.Sprintf("Batch exceeds %d bytes", maxErr.Limit))
+			return
+		case err != nil:
+			log.Warn("🙅 Rejected batch payload", "error", err, "remote_addr", clientIP(r))
+			writeError(w, http.StatusBadRequest, "Malformed batch", fmt.Sprintf("malformed JSON body: %v", err))
+			return
+		case len(req.Entries) == 0:
+			writeError(w, http.StatusBadRequest, "Malformed batch", "entries must not be empty")
+			return
+		case len(req.Entries) > maxBatchEntries:
+			writeError(w, http.StatusBadRequest, "Batch too large", fmt.Sprintf("at most %d entries per batch", maxBatchEntries))
+			return
+		}
+
+		logDir := cfg.DataDir
+		results := make([]BatchWriteResult, len(req.Entries))
+		failed := 0
+		for i, entry := range req.Entries {
+			op := atomic.AddInt64(&writeOpCount, 1)
+			seq, err := writeSeq.next()
+			if err != nil {
+				log.Error("😱 Failed to reserve write sequence number", "entry", i, "error", err)
+				results[i] = BatchWriteResult{Error: err.Error()}
+				failed++
+				continue
+			}
+			filename := logFileName(time.Now(), seq)
+
+			var content string
+			if cfg.Features[featurePlainLogs] {
+				content = renderPlainWriteLog(r, cfg, op, seq, entry.Payload)
+			} else {
+				content = renderWriteLog(r, cfg, op, seq, entry.Payload)
+			}
+
+			if err := writeLogFileCtx(r.Context(), logDir, filepath.Join(logDir, filename), []byte(content)); err != nil {
+				log.Error("😱 Failed to write batch entry", "file", filename, "entry", i, "error", err)
+				results[i] = BatchWriteResult{Filename: filename, Error: err.Error()}
+				failed++
+				continue
+			}
+			recordWrite()
+			results[i] = BatchWriteResult{Filename: filename, Sequence: seq, SizeBytes: len(content)}
+		}
+
+		if r.Context().Err() != nil {
+			log.Warn("🏃 Client disconnected - batch abandoned", "entries", len(req.Entries), "failed", failed)
+			w.WriteHeader(statusClientClosedRequest)
+			return
+		}
+
+		status := http.StatusOK
+		if failed > 0 {
+			status = http.StatusMultiStatus
+			log.Warn("⚠️ Batch write partially failed", "entries", len(req.Entries), "failed", failed)
+		} else {
+			log.Info("🎉 Batch write completed - that's a whole collection!", "entries", len(req.Entries), "dir", logDir)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(results); err != nil {
+			log.Error("😱 Failed to encode batch results JSON", "error", err)
+		}
+	}
+}
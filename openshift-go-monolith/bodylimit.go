@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// bodyLimitMiddleware caps every request body at limit bytes (MAX_BODY_BYTES,
+// or the older MAX_REQUEST_BODY_BYTES). A declared
+// Content-Length over the limit is refused up front with a problem+json 413; a
+// chunked body is cut off by http.MaxBytesReader, which handlers report as
+// 413 when their read fails.
+func bodyLimitMiddleware(limit int64) func(http.Handler) http.Handler {
+	logger.Info("[CONFIG] 📏 Request body limit", "max_bytes", limit)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				atomic.AddInt64(&oversizedBodies, 1)
+				requestLogger(r).Warn("🐘 Request body too large - rejected before reading",
+					"path", r.URL.Path, "content_length", r.ContentLength, "limit_bytes", limit)
+				writeError(w, http.StatusRequestEntityTooLarge, "Payload too large",
+					fmt.Sprintf("Request body exceeds %d bytes", limit))
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// startLogCleanup prunes dir right away and then every interval until ctx is
+// cancelled: files older than retention are deleted, and beyond that only
+// the newest maxFiles are kept. A zero retention (LOG_RETENTION=0) disables
+// cleanup entirely, the file cap included. The returned channel is closed
+// once the loop has exited.
+func startLogCleanup(ctx context.Context, dir string, interval, retention time.Duration, maxFiles int) <-chan struct{} {
+	done := make(chan struct{})
+	if retention == 0 {
+		logger.Warn("[CLEANUP] 🧹 Log cleanup disabled (retention 0) - nothing will be deleted, LOG_MAX_FILES included", "dir", dir)
+		close(done)
+		return done
+	}
+	logger.Info("[CLEANUP] 🧹 Log cleanup scheduled", "dir", dir,
+		"interval", interval.String(), "retention", retention.String(), "max_files", maxFiles)
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			cleanupOldLogs(dir, retention, maxFiles)
+			select {
+			case <-ctx.Done():
+				logger.Info("[SHUTDOWN] 🧹 Log cleanup stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return done
+}
+
+// cleanupOldLogs removes the log files in dir that are past retention or
+// beyond the newest maxFiles, logging each one and the total reclaimed.
+// retention must be positive; startLogCleanup never calls it with zero.
+func cleanupOldLogs(dir string, retention time.Duration, maxFiles int) {
+	entries, err := listLogFiles(dir)
+	if err != nil {
+		logger.Error("[CLEANUP] 💥 Failed to read log directory", "dir", dir, "error", err)
+		return
+	}
+
+	cutoff := time.Now().Add(-retention)
+	var files int
+	var bytes int64
+	// entries is newest first, so anything past maxFiles is the oldest excess
+	for i, entry := range entries {
+		expired := entry.ModifiedAt.Before(cutoff)
+		if !expired && i < maxFiles {
+			continue
+		}
+		err := os.Remove(filepath.Join(dir, entry.Name))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logger.Warn("[CLEANUP] ⚠️ Failed to delete old log file", "file", entry.Name, "error", err)
+			continue
+		}
+		files++
+		bytes += entry.SizeBytes
+		atomic.AddInt64(&filesCleanedCount, 1)
+		logger.Info("[CLEANUP] 🗑️ Deleted log file", "file", entry.Name, "size_bytes", entry.SizeBytes, "expired", expired)
+	}
+
+	if files > 0 {
+		logger.Info("[CLEANUP] ♻️ Old log files deleted - decluttering era", "files", files, "bytes_reclaimed", bytes)
+	} else {
+		logger.Debug("[CLEANUP] ✨ No log files past retention", "dir", dir)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// trustProxy makes clientIP believe the X-Forwarded-For and X-Real-IP
+// headers (TRUST_PROXY). Only enable it behind a proxy that sets them, such
+// as the OpenShift router, or clients can pick their own address.
+var trustProxy bool
+
+// clientIP returns the address a request really came from. Behind a trusted
+// proxy that is the right-most public X-Forwarded-For entry: the router
+// appends the peer it saw, and everything left of that is whatever the
+// client sent. Internal hops on the right are other proxies and are skipped;
+// if every hop is internal the left-most one is the origin. Without
+// X-Forwarded-For X-Real-IP is used, and otherwise, or whenever neither
+// header helps, the peer from RemoteAddr.
+func clientIP(r *http.Request) string {
+	if trustProxy {
+		var hops []net.IP
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(header, ",") {
+				if ip := net.ParseIP(strings.TrimSpace(hop)); ip != nil {
+					hops = append(hops, ip)
+				}
+			}
+		}
+		for i := len(hops) - 1; i >= 0; i-- {
+			if !isInternalIP(hops[i]) {
+				return hops[i].String()
+			}
+		}
+		if len(hops) > 0 {
+			return hops[0].String()
+		}
+		if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+			return ip.String()
+		}
+	}
+	return remoteIP(r)
+}
+
+// isInternalIP reports addresses that belong to proxies and cluster
+// networking rather than to a real client.
+func isInternalIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified()
+}
+
+// remoteIP strips the port from r.RemoteAddr.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
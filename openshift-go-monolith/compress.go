@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"os"
+	"strings"
+)
+
+// gzipLogSuffix marks log files written with ?compress=true.
+const gzipLogSuffix = ".gz"
+
+// gzipBytes compresses data at the default level.
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gzipUncompressedSize reads the original size from the gzip trailer, which
+// stores it modulo 4 GiB - plenty for files capped at MAX_WRITE_BYTES.
+func gzipUncompressedSize(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var trailer [4]byte
+	if _, err := f.Seek(-int64(len(trailer)), io.SeekEnd); err != nil {
+		return 0, err
+	}
+	if _, err := io.ReadFull(f, trailer[:]); err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint32(trailer[:])), nil
+}
+
+// gzipReadCloser closes both the gzip stream and the file underneath it.
+type gzipReadCloser struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g gzipReadCloser) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
+
+// openLogFile opens a log file for reading, transparently decompressing the
+// ones stored as .gz.
+func openLogFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, gzipLogSuffix) {
+		return f, nil
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return gzipReadCloser{Reader: zr, file: f}, nil
+}
@@ -0,0 +1,489 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Where a configuration value came from, in increasing order of precedence.
+const (
+	sourceDefault = "default"
+	sourceFile    = "file"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+)
+
+// envFileResult reports what loadEnvFiles did with one file.
+type envFileResult struct {
+	path string
+	keys int // keys this file contributed to the environment
+	err  error
+}
+
+// envFromFiles records the variables the env files supplied, as opposed to
+// ones the process was started with, so a reload knows which it may change.
+var envFromFiles = make(map[string]bool)
+
+// loadEnvFiles loads base and then base.$APP_ENV (e.g. .env.production),
+// where APP_ENV comes from the system environment or the base file. The
+// environment-specific file overrides the base file, and variables the
+// process was started with override both. Missing files are reported, not
+// fatal. Calling it again re-applies the files with the same precedence.
+func loadEnvFiles(base string) []envFileResult {
+	merged, origin, results := readEnvFiles(base)
+	applyEnvFiles(merged, origin, results)
+	return results
+}
+
+// readEnvFiles reads and merges the files loadEnvFiles would apply, noting
+// for each key the index in results of the file that supplied it.
+func readEnvFiles(base string) (merged map[string]string, origin map[string]int, results []envFileResult) {
+	baseValues, baseErr := godotenv.Read(base)
+
+	appEnv := os.Getenv("APP_ENV")
+	if appEnv == "" || envFromFiles["APP_ENV"] {
+		appEnv = baseValues["APP_ENV"]
+	}
+
+	results = []envFileResult{{path: base, err: baseErr}}
+	merged = make(map[string]string, len(baseValues))
+	origin = make(map[string]int, len(baseValues))
+	for k, v := range baseValues {
+		merged[k], origin[k] = v, 0
+	}
+
+	if appEnv != "" {
+		specific := base + "." + appEnv
+		values, err := godotenv.Read(specific)
+		results = append(results, envFileResult{path: specific, err: err})
+		for k, v := range values {
+			merged[k], origin[k] = v, 1
+		}
+	}
+	return merged, origin, results
+}
+
+// applyEnvFiles sets every merged key the process wasn't started with,
+// counting each against the file it came from.
+func applyEnvFiles(merged map[string]string, origin map[string]int, results []envFileResult) {
+	for k, v := range merged {
+		if _, set := os.LookupEnv(k); set && !envFromFiles[k] {
+			continue
+		}
+		os.Setenv(k, v)
+		envFromFiles[k] = true
+		results[origin[k]].keys++
+	}
+}
+
+// Config mirrors the settings that can be supplied through a YAML file named
+// by CONFIG_FILE. Each field backs the environment variable noted beside it;
+// the environment always wins over the file.
+type Config struct {
+	AppName   string `yaml:"app_name"`    // APP_NAME
+	Env       string `yaml:"environment"` // APP_ENV
+	DBUser    string `yaml:"db_user"`     // APP_DB_USER
+	Port      int    `yaml:"port"`        // APP_PORT
+	DataDir   string `yaml:"data_dir"`    // APP_DATA_DIR
+	LogLevel  string `yaml:"log_level"`   // APP_LOG_LEVEL
+	LogFormat string `yaml:"log_format"`  // APP_LOG_FORMAT
+}
+
+// fileConfig holds the parsed CONFIG_FILE, or nil when none was given.
+var fileConfig *Config
+
+// Sources of the settings that are fixed at startup, reported by /api/config.
+var (
+	listenPortSource = sourceDefault
+	dataDirSource    = sourceDefault
+)
+
+// ConfigValue is one effective setting together with where it came from.
+type ConfigValue struct {
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// configSecretKeys are the credentials /api/config reports, masked, when
+// set, so an operator can see that they are configured without seeing them.
+var configSecretKeys = []string{"API_AUTH_PASS", "API_AUTH_PASS_HASH", "ADMIN_TOKEN"}
+
+// effectiveConfig snapshots the settings the server is running with, keyed
+// by environment variable name. Secret values are replaced with "****".
+func effectiveConfig(cfg *runtimeSettings) map[string]ConfigValue {
+	values := map[string]ConfigValue{
+		"APP_NAME":           {cfg.AppName, envSource("APP_NAME")},
+		"APP_ENV":            {cfg.Env, envSource("APP_ENV")},
+		"APP_DB_USER":        {cfg.DBUser, envSource("APP_DB_USER")},
+		"APP_PORT":           {cfg.Port, listenPortSource},
+		"APP_DATA_DIR":       {cfg.DataDir, dataDirSource},
+		"APP_LOG_LEVEL":      {strings.ToLower(logLevel.Level().String()), envSource("APP_LOG_LEVEL")},
+		"APP_LOG_FORMAT":     {cfg.LogFormat, envSource("APP_LOG_FORMAT")},
+		"MAX_HEADER_BYTES":   {strconv.Itoa(connOptions.maxHeaderBytes), envSource("MAX_HEADER_BYTES")},
+		"DISABLE_KEEPALIVES": {strconv.FormatBool(!connOptions.keepAlives), envSource("DISABLE_KEEPALIVES")},
+	}
+	for _, key := range configSecretKeys {
+		if os.Getenv(key) != "" {
+			values[key] = ConfigValue{"****", envSource(key)}
+		}
+	}
+	return values
+}
+
+// newConfigHandler serves the effective configuration for debugging drift
+// between environments.
+func newConfigHandler(current settingsSource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordRequest(r)
+		log := requestLogger(r)
+		log.Info("🔧 Config request received", "method", r.Method, "path", r.URL.Path, "remote_addr", clientIP(r))
+		if !requireMethod(w, r, http.MethodGet) {
+			return
+		}
+
+		body, err := json.Marshal(effectiveConfig(current()))
+		if err != nil {
+			log.Error("😱 Failed to encode config JSON", "error", err)
+			writeError(w, http.StatusInternalServerError, "Failed to encode config", err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(append(body, '\n'))
+	}
+}
+
+// loadConfigFile parses path as a Config. An empty path is not an error and
+// yields a nil config; unknown keys and mistyped values are.
+func loadConfigFile(path string) (*Config, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cfg Config
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// lookup returns the file value backing the environment variable key, or ""
+// when the file does not set it.
+func (c *Config) lookup(key string) string {
+	if c == nil {
+		return ""
+	}
+	switch key {
+	case "APP_NAME":
+		return c.AppName
+	case "APP_ENV":
+		return c.Env
+	case "APP_DB_USER":
+		return c.DBUser
+	case "APP_PORT":
+		if c.Port != 0 {
+			return strconv.Itoa(c.Port)
+		}
+	case "APP_DATA_DIR":
+		return c.DataDir
+	case "APP_LOG_LEVEL":
+		return c.LogLevel
+	case "APP_LOG_FORMAT":
+		return c.LogFormat
+	}
+	return ""
+}
+
+// legacyEnvKeys maps each APP_-prefixed setting to the unprefixed name it
+// replaced. The old names are still honoured, but only when the new one is
+// unset, and are reported by deprecatedEnvKeys.
+var legacyEnvKeys = map[string]string{
+	"APP_DB_USER":    "DB_USER",
+	"APP_PORT":       "PORT",
+	"APP_DATA_DIR":   "DATA_DIR",
+	"APP_LOG_LEVEL":  "LOG_LEVEL",
+	"APP_LOG_FORMAT": "LOG_FORMAT",
+}
+
+// envValue returns the environment variable key, falling back to its legacy
+// unprefixed name.
+func envValue(key string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	if legacy, ok := legacyEnvKeys[key]; ok {
+		return os.Getenv(legacy)
+	}
+	return ""
+}
+
+// deprecatedEnvKeys returns the legacy names currently supplying a value,
+// mapped to the APP_-prefixed name that should be used instead.
+func deprecatedEnvKeys() map[string]string {
+	used := make(map[string]string)
+	for key, legacy := range legacyEnvKeys {
+		if os.Getenv(key) == "" && os.Getenv(legacy) != "" {
+			used[legacy] = key
+		}
+	}
+	return used
+}
+
+// lookupSetting returns the environment variable key, falling back to the
+// value from CONFIG_FILE.
+func lookupSetting(key string) string {
+	if value := envValue(key); value != "" {
+		return value
+	}
+	return fileConfig.lookup(key)
+}
+
+// cliFlags holds the command-line overrides. Flags win over environment
+// variables, which win over built-in defaults.
+type cliFlags struct {
+	port    string
+	dataDir string
+	envFile string
+
+	// validate and jsonOutput select the -validate dry run and its format.
+	validate   bool
+	jsonOutput bool
+
+	// set records which flags were passed explicitly, keyed by flag name.
+	set map[string]bool
+}
+
+func parseFlags() *cliFlags {
+	f := &cliFlags{set: make(map[string]bool)}
+	flag.StringVar(&f.port, "port", "8080", "port to listen on (overrides APP_PORT)")
+	flag.StringVar(&f.dataDir, "data", "./data/log", "directory for written log files (overrides APP_DATA_DIR)")
+	flag.StringVar(&f.envFile, "env-file", ".env", "path of the env file to load")
+	flag.BoolVar(&f.validate, "validate", false, "check the configuration, print a summary and exit (also VALIDATE_ONLY=true)")
+	flag.BoolVar(&f.jsonOutput, "json", false, "with -validate, print the summary as JSON")
+	flag.Parse()
+
+	flag.Visit(func(fl *flag.Flag) { f.set[fl.Name] = true })
+	return f
+}
+
+// resolveSetting picks the effective value for a setting backed by both a
+// flag and an environment variable, and reports which one supplied it.
+func (f *cliFlags) resolveSetting(flagName, flagValue, envKey, defaultValue string) (string, string) {
+	if f.set[flagName] {
+		return flagValue, sourceFlag
+	}
+	if value := envValue(envKey); value != "" {
+		return value, sourceEnv
+	}
+	if value := fileConfig.lookup(envKey); value != "" {
+		return value, sourceFile
+	}
+	return defaultValue, sourceDefault
+}
+
+// envSource reports whether key is supplied by the environment, the config
+// file, or will fall back to its default.
+func envSource(key string) string {
+	if envValue(key) != "" {
+		return sourceEnv
+	}
+	if fileConfig.lookup(key) != "" {
+		return sourceFile
+	}
+	return sourceDefault
+}
+
+// resolveListenAddr works out the address the server should bind to.
+// LISTEN_ADDR, when set, is used as a full host:port unless the port was
+// given on the command line; otherwise port is bound on BIND_ADDR, or all
+// interfaces when that is empty. The port is returned separately so it can
+// be reported by /api/info. Nothing is validated here; see validateConfig.
+func resolveListenAddr(port, source string) (addr, p string) {
+	if listen := os.Getenv("LISTEN_ADDR"); listen != "" && source != sourceFlag {
+		_, p, _ := net.SplitHostPort(listen)
+		return listen, p
+	}
+	return net.JoinHostPort(os.Getenv("BIND_ADDR"), port), port
+}
+
+// resolveDataDir cleans dir and makes it absolute so logs and API responses
+// show exactly where files land.
+func resolveDataDir(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return filepath.Clean(dir)
+}
+
+// requiredEnvKeys must be set in strict mode; REQUIRED_ENV_VARS adds more.
+var requiredEnvKeys = []string{"APP_NAME", "APP_ENV", "APP_DB_USER"}
+
+// strictEnvMode reports whether missing required settings are fatal:
+// REQUIRE_ENV=true turns it on explicitly, and production always has it.
+func strictEnvMode() bool {
+	if required, err := strconv.ParseBool(os.Getenv("REQUIRE_ENV")); err == nil {
+		return required
+	}
+	return lookupSetting("APP_ENV") == "production"
+}
+
+// missingRequiredEnv returns the required keys that have no value from the
+// environment, an env file or CONFIG_FILE.
+func missingRequiredEnv() []string {
+	keys := slices.Clone(requiredEnvKeys)
+	for _, key := range strings.Split(os.Getenv("REQUIRED_ENV_VARS"), ",") {
+		if key = strings.TrimSpace(key); key != "" && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	var missing []string
+	for _, key := range keys {
+		if lookupSetting(key) == "" {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// knownEnvironments lists the accepted APP_ENV values.
+var knownEnvironments = []string{"development", "staging", "production"}
+
+// validateConfig checks the resolved startup settings and returns one
+// message per violation, so every problem can be fixed in a single pass.
+// A dry run only checks that the data directory could be created, without
+// creating it.
+func validateConfig(addr, port, dir, appEnv string, dryRun bool) []string {
+	var problems []string
+
+	if bind := os.Getenv("BIND_ADDR"); bind != "" && net.ParseIP(bind) == nil {
+		problems = append(problems, fmt.Sprintf("BIND_ADDR %q is not a valid IP address", bind))
+	}
+
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		problems = append(problems, fmt.Sprintf("listen address %q is malformed: %v", addr, err))
+	} else if err := validatePort(port); err != nil {
+		problems = append(problems, fmt.Sprintf("port: %v", err))
+	}
+
+	if admin := os.Getenv("ADMIN_PORT"); admin != "" {
+		if err := validatePort(admin); err != nil {
+			problems = append(problems, fmt.Sprintf("ADMIN_PORT: %v", err))
+		} else if admin == port {
+			problems = append(problems, fmt.Sprintf("ADMIN_PORT %s must differ from the main port", admin))
+		}
+	}
+
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		problems = append(problems, fmt.Sprintf("data directory %s exists but is not a directory", dir))
+	} else if dryRun {
+		if err := checkDataDirAccess(dir); err != nil {
+			problems = append(problems, err.Error())
+		}
+	} else if err := checkDataDirWritable(dir); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	problems = append(problems, validateAuthConfig()...)
+	problems = append(problems, validateCORSConfig()...)
+	problems = append(problems, validateTLSConfig(port)...)
+	problems = append(problems, validateDebugConfig(port)...)
+
+	if !slices.Contains(knownEnvironments, appEnv) {
+		problems = append(problems, fmt.Sprintf("APP_ENV %q is not one of %s", appEnv, strings.Join(knownEnvironments, ", ")))
+	}
+
+	return problems
+}
+
+func validatePort(port string) error {
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("%q is not a valid port number (1-65535)", port)
+	}
+	return nil
+}
+
+// secretKeyMarkers flag env keys whose values must never be logged or
+// served in full.
+var secretKeyMarkers = []string{"PASS", "SECRET", "TOKEN", "KEY"}
+
+func isSecretKey(key string) bool {
+	upper := strings.ToUpper(key)
+	for _, marker := range secretKeyMarkers {
+		if strings.Contains(upper, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactValue masks value when key looks like it holds a secret, keeping
+// only the first and last character as a hint. Values too short for that
+// to be safe are masked entirely.
+func redactValue(key, value string) string {
+	if !isSecretKey(key) || value == "" {
+		return value
+	}
+	runes := []rune(value)
+	if len(runes) < 6 {
+		return "****"
+	}
+	return string(runes[0]) + "****" + string(runes[len(runes)-1])
+}
+
+// splitList splits a comma-separated setting, dropping blanks.
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// envSnapshot returns every environment variable whose name starts with one
+// of prefixes, with secret-looking values replaced by "[REDACTED]".
+func envSnapshot(prefixes []string) map[string]string {
+	snapshot := make(map[string]string)
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		for _, prefix := range prefixes {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			if isSecretKey(key) {
+				value = "[REDACTED]"
+			}
+			snapshot[key] = value
+			break
+		}
+	}
+	return snapshot
+}
+
+// logConfig emits a [CONFIG] startup line for key, masking secrets.
+func logConfig(label, key, value, source string) {
+	logger.Info("[CONFIG] "+label, "value", redactValue(key, value), "source", source)
+}
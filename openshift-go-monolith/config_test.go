@@ -0,0 +1,470 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// clearStartupEnv unsets everything validateConfig looks at, so each case
+// starts from a valid configuration.
+func clearStartupEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range []string{
+		"BIND_ADDR", "ADMIN_PORT", "API_AUTH_USER", "API_AUTH_PASS", "API_AUTH_PASS_HASH",
+		"TLS_CERT_FILE", "TLS_KEY_FILE", "TLS_CERT", "TLS_KEY", "TLS_REDIRECT_PORT",
+		"DEBUG_PPROF_ENABLED", "ENABLE_PPROF", "CORS_ALLOWED_ORIGINS", "CORS_ALLOW_CREDENTIALS",
+	} {
+		t.Setenv(key, "")
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	dataFile := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(dataFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		env    map[string]string
+		addr   string
+		port   string
+		dir    string
+		appEnv string
+		want   []string // substrings, one per expected problem
+	}{
+		{name: "valid", addr: ":8080", port: "8080", appEnv: "production"},
+		{name: "data dir is created", addr: ":8080", port: "8080", dir: "nested/log", appEnv: "staging"},
+		{name: "non-numeric port", addr: ":http", port: "http", appEnv: "development", want: []string{`"http" is not a valid port`}},
+		{name: "port out of range", addr: ":70000", port: "70000", appEnv: "development", want: []string{`"70000" is not a valid port`}},
+		{name: "port zero", addr: ":0", port: "0", appEnv: "development", want: []string{`"0" is not a valid port`}},
+		{name: "malformed listen address", addr: "localhost", port: "", appEnv: "development", want: []string{"listen address \"localhost\" is malformed"}},
+		{name: "bind address not an IP", env: map[string]string{"BIND_ADDR": "my-host"}, addr: "my-host:8080", port: "8080", appEnv: "development", want: []string{`BIND_ADDR "my-host"`}},
+		{name: "admin port clashes", env: map[string]string{"ADMIN_PORT": "8080"}, addr: ":8080", port: "8080", appEnv: "development", want: []string{"ADMIN_PORT 8080 must differ"}},
+		{name: "admin port invalid", env: map[string]string{"ADMIN_PORT": "x"}, addr: ":8080", port: "8080", appEnv: "development", want: []string{"ADMIN_PORT:"}},
+		{name: "data dir is a file", addr: ":8080", port: "8080", dir: dataFile, appEnv: "development", want: []string{"exists but is not a directory"}},
+		{name: "unknown environment", addr: ":8080", port: "8080", appEnv: "prod", want: []string{`APP_ENV "prod" is not one of`}},
+		{name: "cors wildcard with credentials", env: map[string]string{"CORS_ALLOWED_ORIGINS": "*", "CORS_ALLOW_CREDENTIALS": "true"}, addr: ":8080", port: "8080", appEnv: "development", want: []string{"CORS_ALLOW_CREDENTIALS=true cannot be combined"}},
+		{
+			name: "every problem reported at once",
+			addr: ":0", port: "0", dir: dataFile, appEnv: "qa",
+			want: []string{"is not a valid port", "is not a directory", `APP_ENV "qa"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearStartupEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			dir := tt.dir
+			if dir == "" {
+				dir = t.TempDir()
+			} else if !filepath.IsAbs(dir) {
+				dir = filepath.Join(t.TempDir(), dir)
+			}
+
+			problems := validateConfig(tt.addr, tt.port, dir, tt.appEnv, false)
+			if len(problems) != len(tt.want) {
+				t.Fatalf("validateConfig() = %q, want %d problem(s)", problems, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("problem %d = %q, want it to mention %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateConfigUnwritableDataDir(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to any directory")
+	}
+	clearStartupEnv(t)
+	parent := t.TempDir()
+	if err := os.Chmod(parent, 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(parent, 0755) })
+
+	for _, dryRun := range []bool{false, true} {
+		problems := validateConfig(":8080", "8080", filepath.Join(parent, "log"), "development", dryRun)
+		if len(problems) != 1 || !strings.Contains(problems[0], "cannot be created") {
+			t.Fatalf("validateConfig(dryRun=%t) = %q, want the data directory reported", dryRun, problems)
+		}
+	}
+}
+
+func TestValidateConfigDryRunCreatesNothing(t *testing.T) {
+	clearStartupEnv(t)
+	parent := filepath.Join(t.TempDir(), "missing")
+	dir := filepath.Join(parent, "data")
+
+	if problems := validateConfig(":8080", "8080", dir, "development", true); len(problems) != 0 {
+		t.Fatalf("validateConfig() = %q, want no problems", problems)
+	}
+	if _, err := os.Stat(parent); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("stat %s after a dry run: err = %v, want it not to exist", parent, err)
+	}
+}
+
+func TestConfigHandlerNeverLeaksSecrets(t *testing.T) {
+	secrets := map[string]string{
+		"API_AUTH_PASS":      "correct-horse-battery",
+		"API_AUTH_PASS_HASH": "$2a$10$abcdefghijklmnopqrstuv",
+		"ADMIN_TOKEN":        "admin-token-value",
+	}
+	for k, v := range secrets {
+		t.Setenv(k, v)
+	}
+	t.Setenv("APP_DB_USER", "test_user")
+	rec := httptest.NewRecorder()
+	newConfigHandler(fixedSettings(testSettings(t)))(rec, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	for key, value := range secrets {
+		if strings.Contains(body, value) {
+			t.Errorf("response contains the value of %s: %s", key, body)
+		}
+	}
+
+	var got map[string]ConfigValue
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	for key := range secrets {
+		if got[key] != (ConfigValue{"****", sourceEnv}) {
+			t.Errorf("%s = %+v, want **** from env", key, got[key])
+		}
+	}
+	if got["APP_DB_USER"] != (ConfigValue{"test_user", sourceEnv}) {
+		t.Errorf("APP_DB_USER = %+v, want test_user from env", got["APP_DB_USER"])
+	}
+	if got["APP_NAME"].Value != "test-app" {
+		t.Errorf("APP_NAME = %+v, want test-app", got["APP_NAME"])
+	}
+}
+
+func TestConfigHandlerOmitsUnsetSecrets(t *testing.T) {
+	unsetEnv(t, configSecretKeys...)
+	rec := httptest.NewRecorder()
+	newConfigHandler(fixedSettings(testSettings(t)))(rec, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+
+	var got map[string]ConfigValue
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range configSecretKeys {
+		if v, ok := got[key]; ok {
+			t.Errorf("%s = %+v, want it left out while unset", key, v)
+		}
+	}
+}
+
+func TestConfigHandlerRejectsOtherMethods(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newConfigHandler(fixedSettings(testSettings(t)))(rec, httptest.NewRequest(http.MethodPost, "/api/config", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want 405", rec.Code)
+	}
+	if got := rec.Header().Get("Allow"); got != http.MethodGet {
+		t.Errorf("Allow = %q, want GET", got)
+	}
+}
+
+func TestRedactValue(t *testing.T) {
+	tests := []struct {
+		key, value, want string
+	}{
+		{"APP_DB_PASSWORD", "correcthorse", "c****e"},
+		{"db_password", "correcthorse", "c****e"},
+		{"CLIENT_SECRET", "abcdef", "a****f"},
+		{"ADMIN_TOKEN", "tok-12345", "t****5"},
+		{"SIGNING_KEY", "ключ-секрет", "к****т"},
+		{"DB_PASSWORD", "short", "****"},
+		{"DB_PASSWORD", "x", "****"},
+		{"DB_PASSWORD", "", ""},
+		{"APP_NAME", "OpenShift Go Monolith", "OpenShift Go Monolith"},
+		{"APP_DB_USER", "app_user", "app_user"},
+	}
+	for _, tt := range tests {
+		if got := redactValue(tt.key, tt.value); got != tt.want {
+			t.Errorf("redactValue(%q, %q) = %q, want %q", tt.key, tt.value, got, tt.want)
+		}
+	}
+}
+
+// unsetEnv removes keys from the environment for the rest of the test and
+// forgets which ones the env files supplied, restoring both afterwards.
+func unsetEnv(t *testing.T, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		if value, ok := os.LookupEnv(key); ok {
+			t.Cleanup(func() { os.Setenv(key, value) })
+		} else {
+			t.Cleanup(func() { os.Unsetenv(key) })
+		}
+		os.Unsetenv(key)
+	}
+	t.Cleanup(func() { clear(envFromFiles) })
+}
+
+func writeEnvFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadEnvFilesPrecedence(t *testing.T) {
+	unsetEnv(t, "APP_ENV", "ENV_TEST_BASE_ONLY", "ENV_TEST_OVERRIDDEN", "ENV_TEST_SYSTEM")
+	t.Setenv("ENV_TEST_SYSTEM", "from-system")
+
+	base := filepath.Join(t.TempDir(), ".env")
+	writeEnvFile(t, base, "APP_ENV=staging\nENV_TEST_BASE_ONLY=base\nENV_TEST_OVERRIDDEN=base\nENV_TEST_SYSTEM=base\n")
+	writeEnvFile(t, base+".staging", "ENV_TEST_OVERRIDDEN=staging\nENV_TEST_SYSTEM=staging\n")
+
+	results := loadEnvFiles(base)
+
+	want := map[string]string{
+		"APP_ENV":             "staging",
+		"ENV_TEST_BASE_ONLY":  "base",
+		"ENV_TEST_OVERRIDDEN": "staging",
+		"ENV_TEST_SYSTEM":     "from-system",
+	}
+	for key, value := range want {
+		if got := os.Getenv(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want base and staging", len(results))
+	}
+	if results[0].err != nil || results[0].keys != 2 {
+		t.Errorf("base file: %+v, want 2 keys and no error", results[0])
+	}
+	if results[1].path != base+".staging" || results[1].err != nil || results[1].keys != 1 {
+		t.Errorf("staging file: %+v, want 1 key and no error", results[1])
+	}
+}
+
+func TestLoadEnvFilesMissingFiles(t *testing.T) {
+	unsetEnv(t, "APP_ENV")
+	t.Setenv("APP_ENV", "production")
+
+	base := filepath.Join(t.TempDir(), ".env")
+	results := loadEnvFiles(base)
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want base and production", len(results))
+	}
+	for _, r := range results {
+		if r.err == nil || r.keys != 0 {
+			t.Errorf("%s: %+v, want a not-found error and no keys", r.path, r)
+		}
+	}
+}
+
+func TestReloadEnvFileKeepsPrecedence(t *testing.T) {
+	unsetEnv(t, "APP_ENV", "APP_NAME", "ENV_TEST_SYSTEM")
+	t.Setenv("ENV_TEST_SYSTEM", "from-system")
+	prev := settings.Load()
+	t.Cleanup(func() { settings.Store(prev) })
+
+	base := filepath.Join(t.TempDir(), ".env")
+	writeEnvFile(t, base, "APP_ENV=development\nAPP_NAME=base\nENV_TEST_SYSTEM=base\n")
+	writeEnvFile(t, base+".development", "APP_NAME=dev-override\n")
+	loadEnvFiles(base)
+
+	writeEnvFile(t, base, "APP_ENV=development\nAPP_NAME=base-changed\nENV_TEST_SYSTEM=base-changed\n")
+	reloadEnvFile(base)
+	if got := settings.Load().AppName; got != "dev-override" {
+		t.Errorf("after reload AppName = %q, want the .env.development value", got)
+	}
+	if got := os.Getenv("ENV_TEST_SYSTEM"); got != "from-system" {
+		t.Errorf("after reload ENV_TEST_SYSTEM = %q, want the system value", got)
+	}
+
+	writeEnvFile(t, base+".development", "APP_NAME=dev-changed\n")
+	reloadEnvFile(base)
+	if got := settings.Load().AppName; got != "dev-changed" {
+		t.Errorf("after second reload AppName = %q, want dev-changed", got)
+	}
+}
+
+func TestResolveListenAddr(t *testing.T) {
+	tests := []struct {
+		name     string
+		bind     string
+		listen   string
+		port     string
+		source   string
+		wantAddr string
+		wantPort string
+	}{
+		{name: "all interfaces", port: "8080", source: sourceDefault, wantAddr: ":8080", wantPort: "8080"},
+		{name: "bind address", bind: "127.0.0.1", port: "9090", source: sourceEnv, wantAddr: "127.0.0.1:9090", wantPort: "9090"},
+		{name: "ipv6 bind address", bind: "::1", port: "9090", source: sourceEnv, wantAddr: "[::1]:9090", wantPort: "9090"},
+		{name: "listen address wins over env port", bind: "127.0.0.1", listen: "0.0.0.0:7070", port: "9090", source: sourceEnv, wantAddr: "0.0.0.0:7070", wantPort: "7070"},
+		{name: "port flag wins over listen address", listen: "0.0.0.0:7070", port: "9090", source: sourceFlag, wantAddr: ":9090", wantPort: "9090"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("BIND_ADDR", tt.bind)
+			t.Setenv("LISTEN_ADDR", tt.listen)
+			addr, port := resolveListenAddr(tt.port, tt.source)
+			if addr != tt.wantAddr || port != tt.wantPort {
+				t.Errorf("resolveListenAddr(%q, %q) = %q, %q; want %q, %q", tt.port, tt.source, addr, port, tt.wantAddr, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestResolveListenAddrBindsLoopbackOnly(t *testing.T) {
+	t.Setenv("BIND_ADDR", "127.0.0.1")
+	t.Setenv("LISTEN_ADDR", "")
+
+	addr, _ := resolveListenAddr("0", sourceEnv)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	if host != "127.0.0.1" {
+		t.Errorf("listening on %s, want 127.0.0.1 only", ln.Addr())
+	}
+
+	// A second bind to the same address fails naming that address
+	taken := net.JoinHostPort("127.0.0.1", port)
+	addr, _ = resolveListenAddr(port, sourceEnv)
+	if addr != taken {
+		t.Fatalf("resolved %q, want %q", addr, taken)
+	}
+	if _, err := net.Listen("tcp", addr); err == nil || !strings.Contains(err.Error(), taken) {
+		t.Errorf("second bind error = %v, want it to mention %s", err, taken)
+	}
+}
+
+func TestStrictEnvMode(t *testing.T) {
+	tests := []struct {
+		requireEnv, appEnv string
+		want               bool
+	}{
+		{"", "development", false},
+		{"", "production", true},
+		{"true", "development", true},
+		{"false", "production", false},
+		{"not-a-bool", "production", true},
+	}
+	for _, tt := range tests {
+		t.Setenv("REQUIRE_ENV", tt.requireEnv)
+		t.Setenv("APP_ENV", tt.appEnv)
+		if got := strictEnvMode(); got != tt.want {
+			t.Errorf("REQUIRE_ENV=%q APP_ENV=%q: strictEnvMode() = %v, want %v", tt.requireEnv, tt.appEnv, got, tt.want)
+		}
+	}
+}
+
+func TestMissingRequiredEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{
+			name: "all set",
+			env:  map[string]string{"APP_NAME": "app", "APP_ENV": "production", "APP_DB_USER": "user"},
+		},
+		{
+			name: "legacy DB_USER counts",
+			env:  map[string]string{"APP_NAME": "app", "APP_ENV": "production", "DB_USER": "user"},
+		},
+		{
+			name: "nothing set",
+			want: []string{"APP_NAME", "APP_ENV", "APP_DB_USER"},
+		},
+		{
+			name: "one missing",
+			env:  map[string]string{"APP_NAME": "app", "APP_ENV": "production"},
+			want: []string{"APP_DB_USER"},
+		},
+		{
+			name: "extra required keys",
+			env: map[string]string{"APP_NAME": "app", "APP_ENV": "production", "APP_DB_USER": "user",
+				"REQUIRED_ENV_VARS": " FOO, BAR ,,APP_NAME", "FOO": "set"},
+			want: []string{"BAR"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"APP_NAME", "APP_ENV", "APP_DB_USER", "DB_USER", "REQUIRED_ENV_VARS", "FOO", "BAR"} {
+				t.Setenv(key, tt.env[key])
+			}
+			got := missingRequiredEnv()
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("missingRequiredEnv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvSnapshotMasksSecrets(t *testing.T) {
+	env := map[string]string{
+		"APP_DB_USER":     "snapshot_user",
+		"APP_DB_PASSWORD": "snapshot-db-password",
+		"APP_API_TOKEN":   "snapshot-api-token",
+		"DB_SECRET":       "snapshot-db-secret",
+		"DB_HOST":         "db.internal",
+		"OTHER_SETTING":   "not-selected",
+		"OTHER_KEY":       "not-selected-key",
+	}
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+
+	got := envSnapshot([]string{"APP_", "DB_"})
+	want := map[string]string{
+		"APP_DB_USER":     "snapshot_user",
+		"APP_DB_PASSWORD": "[REDACTED]",
+		"APP_API_TOKEN":   "[REDACTED]",
+		"DB_SECRET":       "[REDACTED]",
+		"DB_HOST":         "db.internal",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("snapshot[%s] = %q, want %q", k, got[k], v)
+		}
+	}
+	for _, k := range []string{"OTHER_SETTING", "OTHER_KEY"} {
+		if _, ok := got[k]; ok {
+			t.Errorf("snapshot includes %s, which matches no prefix", k)
+		}
+	}
+
+	cfg := testSettings(t)
+	rec := httptest.NewRecorder()
+	newInfoHandler(fixedSettings(cfg))(rec, httptest.NewRequest(http.MethodGet, "/api/info", nil))
+	body := rec.Body.String()
+	for k, v := range env {
+		if isSecretKey(k) && strings.Contains(body, v) {
+			t.Errorf("/api/info leaks the value of %s: %s", k, body)
+		}
+	}
+	if !strings.Contains(body, "snapshot_user") {
+		t.Errorf("/api/info is missing the APP_DB_USER snapshot: %s", body)
+	}
+}
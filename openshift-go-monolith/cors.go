@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// corsConfig is the cross-origin policy read from the environment at startup.
+type corsConfig struct {
+	origins     []string // allowed origins; "*" allows any
+	methods     string
+	credentials bool
+}
+
+// loadCORSConfig reads CORS_ALLOWED_ORIGINS (comma-separated, "*" for any),
+// CORS_ALLOWED_METHODS and CORS_ALLOW_CREDENTIALS. With no origins
+// configured the middleware adds no headers at all.
+func loadCORSConfig() corsConfig {
+	var origins []string
+	for _, origin := range strings.Split(getEnvOrDefault("CORS_ALLOWED_ORIGINS", ""), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	credentials, _ := strconv.ParseBool(getEnvOrDefault("CORS_ALLOW_CREDENTIALS", "false"))
+	return corsConfig{
+		origins:     origins,
+		methods:     getEnvOrDefault("CORS_ALLOWED_METHODS", "GET, POST, DELETE, OPTIONS"),
+		credentials: credentials,
+	}
+}
+
+// validateCORSConfig refuses credentials for a wildcard origin: that would
+// let any site make authenticated calls on a visitor's behalf.
+func validateCORSConfig() []string {
+	c := loadCORSConfig()
+	if c.credentials && slices.Contains(c.origins, "*") {
+		return []string{"CORS_ALLOW_CREDENTIALS=true cannot be combined with CORS_ALLOWED_ORIGINS=*; list the trusted origins instead"}
+	}
+	return nil
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin, or
+// "" when it is not allowed.
+func (c corsConfig) allowOrigin(origin string) string {
+	if origin == "" {
+		return ""
+	}
+	if slices.Contains(c.origins, "*") {
+		return "*"
+	}
+	if slices.Contains(c.origins, origin) {
+		return origin
+	}
+	return ""
+}
+
+// corsMiddleware answers preflight requests itself with 204 and adds
+// Access-Control-Allow-Origin to everything else from an allowed origin.
+func corsMiddleware(cfg corsConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			allowed := cfg.allowOrigin(origin)
+			if origin != "" {
+				w.Header().Add("Vary", "Origin")
+			}
+			if allowed != "" {
+				w.Header().Set("Access-Control-Allow-Origin", allowed)
+				w.Header().Set("Access-Control-Expose-Headers", requestIDHeader)
+				// Never for "*", which browsers reject on credentialed requests
+				if cfg.credentials && allowed != "*" {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				if allowed != "" {
+					w.Header().Set("Access-Control-Allow-Methods", cfg.methods)
+					if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+						w.Header().Set("Access-Control-Allow-Headers", headers)
+					}
+					w.Header().Set("Access-Control-Max-Age", "600")
+				} else {
+					logger.Debug("🚫 CORS preflight from unlisted origin", "origin", origin, "path", r.URL.Path)
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// persistedCounters is the on-disk form of the counters that survive a
+// restart.
+type persistedCounters struct {
+	TotalRequests int64     `json:"total_requests"`
+	WriteOps      int64     `json:"write_operations"`
+	SavedAt       time.Time `json:"saved_at"`
+}
+
+// loadCounters seeds requestCount, writeCount and writeOpCount from path. A missing file
+// is not an error; the counters simply start at zero.
+func loadCounters(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var saved persistedCounters
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	atomic.StoreInt64(&requestCount, saved.TotalRequests)
+	atomic.StoreInt64(&writeCount, saved.WriteOps)
+	atomic.StoreInt64(&writeOpCount, saved.WriteOps)
+	return nil
+}
+
+// saveCounters writes the current counters to path atomically.
+func saveCounters(path string) error {
+	data, err := json.Marshal(persistedCounters{
+		TotalRequests: atomic.LoadInt64(&requestCount),
+		WriteOps:      atomic.LoadInt64(&writeCount),
+		SavedAt:       time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// startCounterFlusher saves the counters to path every interval and once more
+// when ctx is cancelled. The returned channel is closed after the final save.
+func startCounterFlusher(ctx context.Context, path string, interval time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				if err := saveCounters(path); err != nil {
+					logger.Error("[SHUTDOWN] 💥 Failed to save counters", "file", path, "error", err)
+					return
+				}
+				logger.Info("[SHUTDOWN] 💾 Counters saved", "file", path)
+				return
+			case <-ticker.C:
+				if err := saveCounters(path); err != nil {
+					logger.Warn("⚠️ Failed to flush counters", "file", path, "error", err)
+				}
+			}
+		}
+	}()
+	return done
+}
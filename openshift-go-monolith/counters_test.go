@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// keepCounters restores requestCount, writeCount and writeOpCount once the
+// test is over.
+func keepCounters(t *testing.T) {
+	t.Helper()
+	requests, writes, ops := atomic.LoadInt64(&requestCount), atomic.LoadInt64(&writeCount), atomic.LoadInt64(&writeOpCount)
+	t.Cleanup(func() {
+		atomic.StoreInt64(&requestCount, requests)
+		atomic.StoreInt64(&writeCount, writes)
+		atomic.StoreInt64(&writeOpCount, ops)
+	})
+}
+
+func TestCountersSurviveRestart(t *testing.T) {
+	keepCounters(t)
+	path := filepath.Join(t.TempDir(), "data", "counters.json")
+
+	atomic.StoreInt64(&requestCount, 42)
+	atomic.StoreInt64(&writeCount, 7)
+	if err := saveCounters(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a restart: the process comes back with everything at zero
+	atomic.StoreInt64(&requestCount, 0)
+	atomic.StoreInt64(&writeCount, 0)
+	atomic.StoreInt64(&writeOpCount, 0)
+	if err := loadCounters(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := atomic.LoadInt64(&requestCount); got != 42 {
+		t.Errorf("requestCount = %d, want 42", got)
+	}
+	if got := atomic.LoadInt64(&writeCount); got != 7 {
+		t.Errorf("writeCount = %d, want 7", got)
+	}
+	if got := atomic.AddInt64(&writeOpCount, 1); got != 8 {
+		t.Errorf("next operation number = %d, want 8", got)
+	}
+}
+
+func TestLoadCountersMissingFile(t *testing.T) {
+	keepCounters(t)
+	atomic.StoreInt64(&requestCount, 0)
+	if err := loadCounters(filepath.Join(t.TempDir(), "counters.json")); err != nil {
+		t.Fatalf("loadCounters() = %v, want nil for a missing file", err)
+	}
+	if got := atomic.LoadInt64(&requestCount); got != 0 {
+		t.Errorf("requestCount = %d, want 0", got)
+	}
+}
+
+func TestCounterFlusherSavesOnShutdown(t *testing.T) {
+	keepCounters(t)
+	path := filepath.Join(t.TempDir(), "counters.json")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := startCounterFlusher(ctx, path, time.Hour)
+	atomic.StoreInt64(&requestCount, 5)
+	atomic.StoreInt64(&writeCount, 3)
+	cancel()
+	<-done
+
+	atomic.StoreInt64(&requestCount, 0)
+	atomic.StoreInt64(&writeCount, 0)
+	if err := loadCounters(path); err != nil {
+		t.Fatal(err)
+	}
+	if r, w := atomic.LoadInt64(&requestCount), atomic.LoadInt64(&writeCount); r != 5 || w != 3 {
+		t.Errorf("restored %d requests and %d writes, want 5 and 3", r, w)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"syscall"
+)
+
+// DiskUsage describes the filesystem holding the data directory.
+type DiskUsage struct {
+	TotalBytes  uint64  `json:"total_bytes"`
+	FreeBytes   uint64  `json:"free_bytes"`
+	UsedBytes   uint64  `json:"used_bytes"`
+	UsedPercent float64 `json:"used_percent"`
+}
+
+// statfs is syscall.Statfs, kept in a variable so it can be swapped out.
+var statfs = syscall.Statfs
+
+// diskUsage reports usage of the filesystem holding dir. Free space is what
+// unprivileged users can still allocate, matching what df shows.
+func diskUsage(dir string) (DiskUsage, error) {
+	var st syscall.Statfs_t
+	if err := statfs(dir, &st); err != nil {
+		return DiskUsage{}, err
+	}
+
+	bsize := uint64(st.Bsize)
+	total := st.Blocks * bsize
+	free := st.Bavail * bsize
+	used := (st.Blocks - st.Bfree) * bsize
+
+	usage := DiskUsage{TotalBytes: total, FreeBytes: free, UsedBytes: used}
+	if usable := used + free; usable > 0 {
+		usage.UsedPercent = float64(used) / float64(usable) * 100
+	}
+	return usage, nil
+}
+
+// freeDiskMB reports the space available to unprivileged users on the
+// filesystem holding dir.
+func freeDiskMB(dir string) (uint64, error) {
+	usage, err := diskUsage(dir)
+	if err != nil {
+		return 0, err
+	}
+	return usage.FreeBytes / 1024 / 1024, nil
+}
+
+// newDiskUsageHandler reports how full the data volume is so operators can
+// decide when to expand the PVC. A missing data directory is a 503.
+func newDiskUsageHandler(current settingsSource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordRequest(r)
+		log := requestLogger(r)
+		log.Info("💽 Disk usage request received", "method", r.Method, "path", r.URL.Path, "remote_addr", clientIP(r))
+		dataDir := current().DataDir
+
+		usage, err := diskUsage(dataDir)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				log.Warn("🚧 Data directory missing - no volume to measure", "dir", dataDir)
+				http.Error(w, "Data directory not available", http.StatusServiceUnavailable)
+				return
+			}
+			log.Error("💥 Failed to stat data volume", "dir", dataDir, "error", err)
+			http.Error(w, "Failed to stat data volume", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(usage); err != nil {
+			log.Error("😱 Failed to encode disk usage JSON", "error", err)
+		}
+	}
+}
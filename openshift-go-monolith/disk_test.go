@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+)
+
+// fakeStatfs swaps statfs for one that reports st, or fails with err, until
+// the test is over.
+func fakeStatfs(t *testing.T, st syscall.Statfs_t, err error) {
+	t.Helper()
+	prev := statfs
+	statfs = func(_ string, out *syscall.Statfs_t) error {
+		if err != nil {
+			return err
+		}
+		*out = st
+		return nil
+	}
+	t.Cleanup(func() { statfs = prev })
+}
+
+func TestDiskUsage(t *testing.T) {
+	// 1000 blocks of 4 KiB: 400 free, of which 300 are available to users
+	fakeStatfs(t, syscall.Statfs_t{Bsize: 4096, Blocks: 1000, Bfree: 400, Bavail: 300}, nil)
+
+	usage, err := diskUsage("/data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DiskUsage{
+		TotalBytes:  1000 * 4096,
+		FreeBytes:   300 * 4096,
+		UsedBytes:   600 * 4096,
+		UsedPercent: float64(600*4096) / float64(900*4096) * 100,
+	}
+	if usage != want {
+		t.Errorf("diskUsage() = %+v, want %+v", usage, want)
+	}
+}
+
+func TestDiskUsageHandler(t *testing.T) {
+	fakeStatfs(t, syscall.Statfs_t{Bsize: 1024, Blocks: 100, Bfree: 75, Bavail: 75}, nil)
+
+	rec := httptest.NewRecorder()
+	newDiskUsageHandler(fixedSettings(testSettings(t)))(rec, httptest.NewRequest(http.MethodGet, "/api/diskusage", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var got DiskUsage
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.TotalBytes != 102400 || got.UsedBytes != 25600 || got.UsedPercent != 25 {
+		t.Errorf("got %+v, want 100 KiB total, 25 KiB used, 25%%", got)
+	}
+}
+
+func TestDiskUsageHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"missing data directory", syscall.ENOENT, http.StatusServiceUnavailable},
+		{"statfs failure", syscall.EIO, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeStatfs(t, syscall.Statfs_t{}, tt.err)
+			rec := httptest.NewRecorder()
+			newDiskUsageHandler(fixedSettings(testSettings(t)))(rec, httptest.NewRequest(http.MethodGet, "/api/diskusage", nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Feature flags accepted in FEATURES.
+const (
+	featureDisableWrite = "disable_write" // /api/write answers 403
+	featurePlainLogs    = "plain_logs"    // write logs without the emoji template
+)
+
+var knownFeatures = []string{featureDisableWrite, featurePlainLogs}
+
+// featureSet is the set of enabled feature flags.
+type featureSet map[string]bool
+
+// parseFeatures splits a comma-separated FEATURES value. Names are
+// case-insensitive; ones this build doesn't know are returned separately so
+// they can be warned about rather than silently ignored.
+func parseFeatures(raw string) (enabled featureSet, unknown []string) {
+	enabled = make(featureSet)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(knownFeatures, name) {
+			unknown = append(unknown, name)
+			continue
+		}
+		enabled[name] = true
+	}
+	return enabled, unknown
+}
+
+// writeFeatureDisabled answers 403 for a request a feature flag turns away,
+// naming the flag so the client can tell it apart from an auth failure.
+func writeFeatureDisabled(w http.ResponseWriter, feature, detail string) {
+	writeProblem(w, Problem{
+		Title:   "Disabled by feature flag",
+		Status:  http.StatusForbidden,
+		Detail:  detail,
+		Feature: feature,
+	})
+}
+
+// names lists the enabled flags in a stable order.
+func (f featureSet) names() []string {
+	names := make([]string, 0, len(f))
+	for _, name := range knownFeatures {
+		if f[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// renderPlainWriteLog is the plain_logs alternative to renderWriteLog: one
+// key: value per line, nothing decorative.
+func renderPlainWriteLog(r *http.Request, cfg *runtimeSettings, op, seq int64, payload string) string {
+	hostname, _ := os.Hostname()
+	var b strings.Builder
+	fmt.Fprintf(&b, "timestamp: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&b, "operation: %d\n", op)
+	fmt.Fprintf(&b, "sequence: %d\n", seq)
+	fmt.Fprintf(&b, "application: %s\n", cfg.AppName)
+	fmt.Fprintf(&b, "environment: %s\n", cfg.Env)
+	fmt.Fprintf(&b, "hostname: %s\n", hostname)
+	fmt.Fprintf(&b, "method: %s\n", r.Method)
+	fmt.Fprintf(&b, "path: %s\n", r.URL.Path)
+	fmt.Fprintf(&b, "user_agent: %s\n", r.UserAgent())
+	fmt.Fprintf(&b, "remote_addr: %s\n", clientIP(r))
+	if payload != "" {
+		fmt.Fprintf(&b, "payload: %s\n", payload)
+	}
+	return b.String()
+}
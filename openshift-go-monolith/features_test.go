@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseFeatures(t *testing.T) {
+	tests := []struct {
+		raw         string
+		wantEnabled []string
+		wantUnknown []string
+	}{
+		{"", nil, nil},
+		{"disable_write", []string{featureDisableWrite}, nil},
+		{" Plain_Logs , DISABLE_WRITE ,,", []string{featureDisableWrite, featurePlainLogs}, nil},
+		{"plain_logs,turbo,warp_drive", []string{featurePlainLogs}, []string{"turbo", "warp_drive"}},
+	}
+	for _, tt := range tests {
+		enabled, unknown := parseFeatures(tt.raw)
+		if got := strings.Join(enabled.names(), ","); got != strings.Join(tt.wantEnabled, ",") {
+			t.Errorf("parseFeatures(%q) enabled %q, want %q", tt.raw, got, tt.wantEnabled)
+		}
+		if strings.Join(unknown, ",") != strings.Join(tt.wantUnknown, ",") {
+			t.Errorf("parseFeatures(%q) unknown %q, want %q", tt.raw, unknown, tt.wantUnknown)
+		}
+	}
+}
+
+func TestWriteHandlerDisableWrite(t *testing.T) {
+	cfg := testSettings(t)
+	cfg.Features, _ = parseFeatures("disable_write")
+	useWriteSequence(t, cfg.DataDir)
+
+	for _, h := range []http.HandlerFunc{newWriteHandler(fixedSettings(cfg)), newBatchWriteHandler(fixedSettings(cfg))} {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodPost, "/api/write", strings.NewReader(`{"entries":[{}]}`)))
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("status = %d, want 403", rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+			t.Errorf("Content-Type = %q, want application/problem+json", ct)
+		}
+		var body Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("403 body is not JSON: %v", err)
+		}
+		if body.Feature != featureDisableWrite || body.Status != http.StatusForbidden || body.Detail == "" {
+			t.Errorf("403 body = %+v, want the problem and the feature name", body)
+		}
+	}
+
+	entries, err := os.ReadDir(cfg.DataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".txt") {
+			t.Errorf("disabled write still created %s", e.Name())
+		}
+	}
+}
+
+func TestWriteHandlerPlainLogs(t *testing.T) {
+	for _, plain := range []bool{false, true} {
+		cfg := testSettings(t)
+		cfg.Features = featureSet{featurePlainLogs: plain}
+		useWriteSequence(t, cfg.DataDir)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/write", nil)
+		req.Header.Set("Accept", mimeJSON)
+		rec := httptest.NewRecorder()
+		newWriteHandler(fixedSettings(cfg))(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("plain_logs=%v: status = %d, want 200", plain, rec.Code)
+		}
+		var result WriteResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		content, err := os.ReadFile(filepath.Join(cfg.DataDir, result.Filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.HasPrefix(string(content), "timestamp: "); got != plain {
+			t.Errorf("plain_logs=%v: file starts %q", plain, strings.SplitN(string(content), "\n", 2)[0])
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// cgroup files holding the container CPU limit: cpu.max on cgroup v2, the
+// CFS quota/period pair on v1.
+const (
+	cgroupV2CPUMax   = "/sys/fs/cgroup/cpu.max"
+	cgroupV1CPUQuota = "/sys/fs/cgroup/cpu/cpu.cfs_quota_us"
+	cgroupV1CPUPer   = "/sys/fs/cgroup/cpu/cpu.cfs_period_us"
+)
+
+// tuneGOMAXPROCS caps GOMAXPROCS at the container's CPU limit so a pod with
+// a 500m limit doesn't schedule across every core on the node and get
+// throttled. An explicit GOMAXPROCS env var is already applied by the
+// runtime and is left alone. Without a limit the runtime default stands.
+func tuneGOMAXPROCS() {
+	if os.Getenv("GOMAXPROCS") != "" {
+		logger.Info("[CONFIG] 🧮 GOMAXPROCS", "value", runtime.GOMAXPROCS(0), "source", "env")
+		return
+	}
+
+	quota, err := cgroupCPUQuota()
+	if err != nil {
+		logger.Info("[CONFIG] 🧮 GOMAXPROCS", "value", runtime.GOMAXPROCS(0), "source", "default", "reason", err.Error())
+		return
+	}
+
+	procs := max(1, int(math.Floor(quota)))
+	if procs < runtime.GOMAXPROCS(0) {
+		runtime.GOMAXPROCS(procs)
+	}
+	logger.Info("[CONFIG] 🧮 GOMAXPROCS", "value", runtime.GOMAXPROCS(0), "source", "cgroup", "cpu_quota", quota)
+}
+
+// errNoCPULimit means the container runs without a CPU quota.
+var errNoCPULimit = errors.New("no cgroup CPU limit")
+
+// cgroupCPUQuota returns the CPU limit in cores, trying cgroup v2 first.
+func cgroupCPUQuota() (float64, error) {
+	if raw, err := os.ReadFile(cgroupV2CPUMax); err == nil {
+		fields := strings.Fields(string(raw))
+		if len(fields) != 2 {
+			return 0, errors.New("malformed " + cgroupV2CPUMax)
+		}
+		if fields[0] == "max" {
+			return 0, errNoCPULimit
+		}
+		return cpuQuotaRatio(fields[0], fields[1])
+	}
+
+	quota, err := os.ReadFile(cgroupV1CPUQuota)
+	if err != nil {
+		return 0, errNoCPULimit
+	}
+	period, err := os.ReadFile(cgroupV1CPUPer)
+	if err != nil {
+		return 0, errNoCPULimit
+	}
+	if strings.TrimSpace(string(quota)) == "-1" {
+		return 0, errNoCPULimit
+	}
+	return cpuQuotaRatio(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+}
+
+func cpuQuotaRatio(quota, period string) (float64, error) {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil {
+		return 0, err
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0, errors.New("invalid cgroup CPU period " + strconv.Quote(period))
+	}
+	return q / p, nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// withH2C lets cleartext HTTP/2 clients (prior knowledge or Upgrade: h2c)
+// talk to handler on the same port as HTTP/1.1 when ENABLE_H2C=true. Over
+// TLS, HTTP/2 is negotiated through ALPN instead, so handler is returned
+// unchanged.
+func withH2C(handler http.Handler, useTLS bool) http.Handler {
+	enabled, _ := strconv.ParseBool(getEnvOrDefault("ENABLE_H2C", "false"))
+	if !enabled {
+		return handler
+	}
+	if useTLS {
+		logger.Info("[CONFIG] 🚄 ENABLE_H2C ignored - TLS already negotiates HTTP/2")
+		return handler
+	}
+	logger.Info("[CONFIG] 🚄 h2c enabled - HTTP/1.1 and cleartext HTTP/2 on one port")
+	return h2c.NewHandler(handler, &http2.Server{})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/http2"
+)
+
+func TestH2CServesStats(t *testing.T) {
+	t.Setenv("ENABLE_H2C", "true")
+	srv := httptest.NewServer(withH2C(newStatsHandler(fixedSettings(testSettings(t))), false))
+	defer srv.Close()
+
+	// Prior-knowledge HTTP/2 over a plain TCP connection
+	h2 := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+	for _, tt := range []struct {
+		name   string
+		client *http.Client
+		proto  int
+	}{
+		{"h2c", h2, 2},
+		{"http1", srv.Client(), 1},
+	} {
+		resp, err := tt.client.Get(srv.URL + "/api/stats")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var stats Stats
+		err = json.NewDecoder(resp.Body).Decode(&stats)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("%s: decoding stats: %v", tt.name, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: status = %d, want 200", tt.name, resp.StatusCode)
+		}
+		if resp.ProtoMajor != tt.proto {
+			t.Errorf("%s: served over %s, want HTTP/%d", tt.name, resp.Proto, tt.proto)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// dbCheckTimeout bounds the readiness probe's connection attempt to DB_ADDR.
+const dbCheckTimeout = time.Second
+
+// ReadinessStatus is the body returned by /readyz and /healthz/ready. Reason
+// repeats the first of Reasons for callers that predate the list.
+type ReadinessStatus struct {
+	Ready   bool     `json:"ready"`
+	Reason  string   `json:"reason,omitempty"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// healthzHandler is the liveness probe behind /healthz/live and its older
+// aliases. It only proves the process can serve HTTP and deliberately never
+// touches the disk. The answer is JSON unless the client asks for text.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	recordRequest(r)
+	requestLogger(r).Debug("❤️ Health check request - checking the vibes...", "remote_addr", clientIP(r))
+	w.Header().Add("Vary", "Accept")
+	if negotiateContentType(r, []string{mimeJSON, mimeText}) == mimeJSON {
+		w.Header().Set("Content-Type", mimeJSON)
+		w.Write([]byte(`{"status":"OK"}` + "\n"))
+		return
+	}
+	w.Header().Set("Content-Type", mimeText+"; charset=utf-8")
+	w.Write([]byte("OK"))
+}
+
+// newReadyzHandler serves the readiness probe. The pod reports not ready as
+// soon as a shutdown starts, and otherwise only once it has been up for
+// READINESS_DELAY_SEC (default 5), the data directory accepts writes, the
+// volume has at least MIN_FREE_DISK_MB (default 50) free and, when DB_ADDR is
+// set, the database accepts connections. Every failing check is listed.
+func newReadyzHandler(current settingsSource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordRequest(r)
+		log := requestLogger(r)
+
+		status := ReadinessStatus{Ready: true}
+		code := http.StatusOK
+		if reasons := checkReadiness(current()); len(reasons) > 0 {
+			log.Warn("🚧 Readiness check failed - not ready to serve", "dir", current().DataDir, "reasons", reasons)
+			status = ReadinessStatus{Ready: false, Reason: reasons[0], Reasons: reasons}
+			code = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			log.Error("😱 Failed to encode readiness JSON", "error", err)
+		}
+	}
+}
+
+// checkReadiness runs every readiness check and returns why each failing one
+// failed, or nothing when the pod is ready.
+func checkReadiness(cfg *runtimeSettings) []string {
+	var reasons []string
+	if draining.Load() {
+		reasons = append(reasons, "shutting down")
+	}
+
+	if up := time.Since(startTime); up < cfg.ReadinessDelay {
+		reasons = append(reasons, fmt.Sprintf("warming up: running for %s, need %s", up.Round(time.Millisecond), cfg.ReadinessDelay))
+	}
+
+	// Free space can't be measured on a directory that isn't there
+	if err := checkDataDirWritable(cfg.DataDir); err != nil {
+		reasons = append(reasons, err.Error())
+	} else if freeMB, err := freeDiskMB(cfg.DataDir); err != nil {
+		reasons = append(reasons, fmt.Sprintf("cannot stat data volume: %v", err))
+	} else if freeMB < cfg.MinFreeDiskMB {
+		reasons = append(reasons, fmt.Sprintf("low disk space on data volume: %d MB free, need %d MB", freeMB, cfg.MinFreeDiskMB))
+	}
+
+	if cfg.DBAddr != "" {
+		conn, err := net.DialTimeout("tcp", cfg.DBAddr, dbCheckTimeout)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("database %s unreachable: %v", cfg.DBAddr, err))
+		} else {
+			conn.Close()
+		}
+	}
+	return reasons
+}
+
+// checkDataDirWritable makes sure dir exists and that a file can be created
+// in it.
+func checkDataDirWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("data directory %s cannot be created: %w", dir, err)
+	}
+
+	f, err := os.CreateTemp(dir, ".readyz-*")
+	if err != nil {
+		return fmt.Errorf("data directory %s is not writable: %w", dir, err)
+	}
+	name := f.Name()
+	f.Close()
+	os.Remove(name)
+	return nil
+}
+
+// checkDataDirAccess is checkDataDirWritable for a dry run: it creates
+// nothing, and instead asks whether dir, or the nearest ancestor that
+// exists, could take writes.
+func checkDataDirAccess(dir string) error {
+	existing := filepath.Clean(dir)
+	for {
+		if _, err := os.Stat(existing); err == nil {
+			break
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("data directory %s cannot be checked: %w", dir, err)
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		existing = parent
+	}
+
+	if err := unix.Access(existing, unix.W_OK|unix.X_OK); err != nil {
+		if existing == filepath.Clean(dir) {
+			return fmt.Errorf("data directory %s is not writable: %w", dir, err)
+		}
+		return fmt.Errorf("data directory %s cannot be created: %s is not writable: %w", dir, existing, err)
+	}
+	return nil
+}
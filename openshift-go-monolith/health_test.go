@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLivenessAliasesAnswerAlike(t *testing.T) {
+	pass := func(h http.Handler) http.Handler { return h }
+	mux, _ := buildMux(appRoutes(fixedSettings(testSettings(t)), pass, pass, pass), false, false)
+
+	for _, accept := range []string{"", "text/plain"} {
+		want := ""
+		for _, path := range []string{"/healthz/live", "/healthz", "/livez", "/health"} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if accept != "" {
+				req.Header.Set("Accept", accept)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("%s: status = %d, want 200", path, rec.Code)
+			}
+			ct := rec.Header().Get("Content-Type")
+			if ct == "" {
+				t.Errorf("%s: no Content-Type", path)
+			}
+			got := ct + " " + rec.Body.String()
+			if want == "" {
+				want = got
+			} else if got != want {
+				t.Errorf("%s with Accept %q answered %q, /healthz/live answered %q", path, accept, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// inflightRequests is how many limited requests are being served now.
+	inflightRequests int64
+	// inflightRejected counts requests turned away by the concurrency cap.
+	inflightRejected int64
+)
+
+// inflightExempt lists the mux routes that never take a slot. The app log
+// stream stays open for as long as the client watches, and holding a slot
+// that long would let a few viewers starve every other request.
+var inflightExempt = map[string]bool{
+	"/api/applogs/stream": true,
+}
+
+// inflightMiddleware caps how many requests run at once with a semaphore of
+// size max. A request arriving at the cap waits up to wait for a slot and
+// then gets 503 with Retry-After, so a load spike queues briefly instead of
+// piling up goroutines and file handles. Routes in inflightExempt bypass the
+// cap.
+func inflightMiddleware(max int, wait time.Duration) func(http.Handler) http.Handler {
+	slots := make(chan struct{}, max)
+	logger.Info("[CONFIG] 🚥 Concurrency limit", "max_inflight", max, "wait", wait.String())
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if inflightExempt[patternRoute(r.Pattern)] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			select {
+			case slots <- struct{}{}:
+			default:
+				timer := time.NewTimer(wait)
+				select {
+				case slots <- struct{}{}:
+					timer.Stop()
+				case <-timer.C:
+					atomic.AddInt64(&inflightRejected, 1)
+					requestLogger(r).Warn("🚥 Too many requests in flight - shedding load",
+						"path", r.URL.Path, "max_inflight", max)
+					w.Header().Set("Retry-After", "1")
+					http.Error(w, "Server busy, try again", http.StatusServiceUnavailable)
+					return
+				case <-r.Context().Done():
+					timer.Stop()
+					w.WriteHeader(statusClientClosedRequest)
+					return
+				}
+			}
+			atomic.AddInt64(&inflightRequests, 1)
+			defer func() {
+				atomic.AddInt64(&inflightRequests, -1)
+				<-slots
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
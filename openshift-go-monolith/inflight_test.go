@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInflightMiddlewareUnderFlood(t *testing.T) {
+	const limit, flood = 3, 20
+	rejectedBefore := atomic.LoadInt64(&inflightRejected)
+
+	var running, peak int64
+	release := make(chan struct{})
+	h := inflightMiddleware(limit, 50*time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&running, 1)
+		for {
+			p := atomic.LoadInt64(&peak)
+			if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt64(&running, -1)
+	}))
+
+	recs := make([]*httptest.ResponseRecorder, flood)
+	var wg sync.WaitGroup
+	for i := range recs {
+		recs[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(rec *httptest.ResponseRecorder) {
+			defer wg.Done()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/info", nil))
+		}(recs[i])
+	}
+
+	// Everything past the cap gives up after the wait; then let the rest finish
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt64(&inflightRejected)-rejectedBefore < flood-limit {
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d requests shed, want %d", atomic.LoadInt64(&inflightRejected)-rejectedBefore, flood-limit)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := atomic.LoadInt64(&inflightRequests); got != limit {
+		t.Errorf("inflightRequests = %d during the flood, want %d", got, limit)
+	}
+	close(release)
+	wg.Wait()
+
+	if peak > limit {
+		t.Errorf("%d requests ran at once, want at most %d", peak, limit)
+	}
+	var ok, busy int
+	for _, rec := range recs {
+		switch rec.Code {
+		case http.StatusOK:
+			ok++
+		case http.StatusServiceUnavailable:
+			busy++
+			if rec.Header().Get("Retry-After") == "" {
+				t.Error("503 without Retry-After")
+			}
+		default:
+			t.Errorf("unexpected status %d", rec.Code)
+		}
+	}
+	if ok != limit || busy != flood-limit {
+		t.Errorf("got %d ok and %d rejected, want %d and %d", ok, busy, limit, flood-limit)
+	}
+	if got := atomic.LoadInt64(&inflightRequests); got != 0 {
+		t.Errorf("inflightRequests = %d after the flood, want 0", got)
+	}
+}
+
+func TestInflightMiddlewareExemptsStreams(t *testing.T) {
+	inflight := inflightMiddleware(1, 10*time.Millisecond)
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	hold := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/api/info", inflight(hold))
+	mux.Handle("/api/applogs/stream", inflight(hold))
+
+	var wg sync.WaitGroup
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		}()
+		return rec
+	}
+
+	// Two open streams must not use up the only slot
+	serve("/api/applogs/stream")
+	serve("/api/applogs/stream")
+	<-started
+	<-started
+	info := serve("/api/info")
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("/api/info never got a slot while streams were open")
+	}
+	close(release)
+	wg.Wait()
+	if info.Code != http.StatusOK {
+		t.Errorf("/api/info status = %d, want 200", info.Code)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// appInfoCache keeps the last /api/info body for INFO_CACHE_TTL_SEC so busy
+// clients don't cost a hostname lookup and an environment scan each. A
+// settings reload invalidates it straight away.
+type appInfoCache struct {
+	mu      sync.Mutex
+	info    AppInfo
+	cfg     *runtimeSettings
+	expires time.Time
+}
+
+// get returns the cached info for cfg, or builds and stores a fresh one. The
+// bool reports a cache hit.
+func (c *appInfoCache) get(cfg *runtimeSettings, build func() AppInfo) (AppInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if c.cfg == cfg && now.Before(c.expires) {
+		return c.info, true
+	}
+	c.info, c.cfg, c.expires = build(), cfg, now.Add(cfg.InfoCacheTTL)
+	return c.info, false
+}
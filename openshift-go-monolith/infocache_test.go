@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestInfoHandlerCache(t *testing.T) {
+	for _, tt := range []struct {
+		ttl  time.Duration
+		want []string
+	}{
+		{0, []string{"MISS", "MISS"}},
+		{time.Minute, []string{"MISS", "HIT"}},
+	} {
+		cfg := testSettings(t)
+		cfg.InfoCacheTTL = tt.ttl
+		h := newInfoHandler(fixedSettings(cfg))
+		for i, want := range tt.want {
+			rec := httptest.NewRecorder()
+			h(rec, httptest.NewRequest(http.MethodGet, "/api/info", nil))
+			if got := rec.Header().Get("X-Cache"); got != want {
+				t.Errorf("ttl %s, request %d: X-Cache = %q, want %q", tt.ttl, i+1, got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkInfoHandler(b *testing.B) {
+	for _, bm := range []struct {
+		name string
+		ttl  time.Duration
+	}{
+		{"uncached", 0},
+		{"cached", 5 * time.Second},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			cfg := &runtimeSettings{AppName: "bench-app", Env: "production", InfoEnvPrefixes: []string{"APP_", "DB_"}, InfoCacheTTL: bm.ttl}
+			h := newInfoHandler(fixedSettings(cfg))
+			req := httptest.NewRequest(http.MethodGet, "/api/info", nil)
+			b.ReportAllocs()
+			for b.Loop() {
+				h(httptest.NewRecorder(), req)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultLogListLimit = 20
+	maxLogListLimit     = 100
+)
+
+// logFileNamePattern matches the names the write handlers generate, plus the
+// ones older releases did: no suffix, or a two-digit batch index. Anything
+// else is rejected before touching the filesystem, which rules out traversal.
+var logFileNamePattern = regexp.MustCompile(`^\d{8}-\d{6}(-\d+)?-log\.txt(\.gz)?$`)
+
+// logFileName names the log file for write sequence number seq, made at t.
+// The sequence keeps names unique however many writes land in one second.
+func logFileName(t time.Time, seq int64) string {
+	return fmt.Sprintf("%s-%d-log.txt", t.Format("20060102-150405"), seq)
+}
+
+// LogFileEntry describes one file written by writeHandler. SizeBytes is the
+// size on disk; UncompressedBytes is what a reader gets back.
+type LogFileEntry struct {
+	Name              string    `json:"name"`
+	SizeBytes         int64     `json:"size_bytes"`
+	UncompressedBytes int64     `json:"uncompressed_size_bytes"`
+	Compressed        bool      `json:"compressed"`
+	ModifiedAt        time.Time `json:"modified_at"`
+}
+
+// newListLogsHandler returns the log files in the data directory, newest
+// first. ?limit=N caps the number returned (default 20, max 100).
+func newListLogsHandler(current settingsSource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordRequest(r)
+		log := requestLogger(r)
+		log.Info("🗂️ Log listing request received", "method", r.Method, "path", r.URL.Path, "remote_addr", clientIP(r))
+		if !requireMethod(w, r, http.MethodGet) {
+			return
+		}
+
+		limit := defaultLogListLimit
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 {
+				http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+				return
+			}
+			limit = min(n, maxLogListLimit)
+		}
+
+		dataDir := current().DataDir
+		entries, err := listLogFiles(dataDir)
+		if err != nil {
+			log.Error("💥 Failed to read log directory", "dir", dataDir, "error", err)
+			http.Error(w, "Failed to read log directory", http.StatusInternalServerError)
+			return
+		}
+		if len(entries) > limit {
+			entries = entries[:limit]
+		}
+
+		log.Debug("📋 Log files collected", "dir", dataDir, "count", len(entries))
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(entries); err != nil {
+			log.Error("😱 Failed to encode log listing JSON", "error", err)
+		}
+	}
+}
+
+// listLogFiles reads dir and returns its .txt and .txt.gz files sorted
+// newest-first. A missing directory yields an empty list rather than an error.
+func listLogFiles(dir string) ([]LogFileEntry, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []LogFileEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]LogFileEntry, 0, len(dirEntries))
+	for _, de := range dirEntries {
+		compressed := strings.HasSuffix(de.Name(), ".txt"+gzipLogSuffix)
+		if de.IsDir() || !(compressed || strings.HasSuffix(de.Name(), ".txt")) {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			// File vanished between ReadDir and Info; skip it
+			continue
+		}
+		entry := LogFileEntry{
+			Name:              de.Name(),
+			SizeBytes:         info.Size(),
+			UncompressedBytes: info.Size(),
+			Compressed:        compressed,
+			ModifiedAt:        info.ModTime(),
+		}
+		if compressed {
+			if size, err := gzipUncompressedSize(filepath.Join(dir, de.Name())); err == nil {
+				entry.UncompressedBytes = size
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModifiedAt.After(entries[j].ModifiedAt)
+	})
+	return entries, nil
+}
+
+// validateLogFileName rejects anything that could escape the data
+// directory, then anything writeHandler would not have generated.
+func validateLogFileName(name string) error {
+	if name == "" || strings.Contains(name, "..") || strings.ContainsAny(name, `/\`) || filepath.Base(name) != name {
+		return errors.New("invalid log file name: path separators and '..' are not allowed")
+	}
+	if !logFileNamePattern.MatchString(name) {
+		return errors.New("invalid log file name: expected YYYYMMDD-HHMMSS[-SEQ]-log.txt or .txt.gz")
+	}
+	return nil
+}
+
+// newGetLogHandler streams a single log file from the data directory as
+// plain text, decompressing .gz files on the way out.
+func newGetLogHandler(current settingsSource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordRequest(r)
+		log := requestLogger(r)
+		name := r.PathValue("filename")
+		log.Info("📖 Log file request received", "file", name, "remote_addr", clientIP(r))
+
+		if err := validateLogFileName(name); err != nil {
+			log.Warn("🚫 Rejected log file name", "file", name, "error", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		f, err := openLogFile(filepath.Join(current().DataDir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			http.Error(w, "Log file not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Error("💥 Failed to open log file", "file", name, "error", err)
+			http.Error(w, "Failed to open log file", http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		n, err := io.Copy(w, f)
+		if err != nil {
+			log.Error("😱 Failed to stream log file", "file", name, "error", err)
+			return
+		}
+		log.Debug("📤 Log file streamed", "file", name, "bytes", n)
+	}
+}
+
+// newDeleteLogHandler removes a single log file so operators can reclaim
+// volume space. The X-Confirm-Delete: true header is required so crawlers
+// and scanners poking at URLs can't delete anything by accident.
+func newDeleteLogHandler(current settingsSource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordRequest(r)
+		log := requestLogger(r)
+		name := r.PathValue("filename")
+		log.Info("🗑️ Log delete request received", "file", name, "remote_addr", clientIP(r))
+
+		if err := validateLogFileName(name); err != nil {
+			log.Warn("🚫 Rejected log file name", "file", name, "error", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("X-Confirm-Delete") != "true" {
+			http.Error(w, "Missing X-Confirm-Delete: true header", http.StatusBadRequest)
+			return
+		}
+
+		path := filepath.Join(current().DataDir, name)
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			http.Error(w, "Log file not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Error("💥 Failed to stat log file", "file", name, "error", err)
+			http.Error(w, "Failed to delete log file", http.StatusInternalServerError)
+			return
+		}
+
+		if err := os.Remove(path); err != nil {
+			log.Error("💥 Failed to delete log file", "file", name, "error", err)
+			http.Error(w, "Failed to delete log file", http.StatusInternalServerError)
+			return
+		}
+
+		atomic.AddInt64(&deleteCount, 1)
+		log.Info("🧹 Log file deleted - space reclaimed", "file", name, "bytes_reclaimed", info.Size())
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
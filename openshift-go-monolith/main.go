@@ -1,120 +1,371 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
 	"sync/atomic"
+	"syscall"
 	"time"
-
-	"github.com/joho/godotenv"
 )
 
 var (
-	startTime    = time.Now()
-	requestCount int64
-	writeCount   int64
-	logger       *log.Logger
+	startTime         = time.Now()
+	requestCount      int64
+	writeCount        int64
+	panicCount        int64
+	deleteCount       int64
+	filesCleanedCount int64
+	oversizedBodies   int64
+	logger            *slog.Logger
+
+	// writeOpCount hands out write operation numbers. It runs ahead of
+	// writeCount, which only counts writes that landed, by the ones that
+	// failed or were abandoned.
+	writeOpCount int64
+
+	// responseCount and errorResponseCount are tallied by loggingMiddleware
+	// for the error rate in /api/stats; errors are 4xx and 5xx.
+	responseCount      int64
+	errorResponseCount int64
+
+	// logLevel is the minimum level the logger emits, set from LOG_LEVEL.
+	logLevel = new(slog.LevelVar)
+
+	// listenPort is the port the HTTP server is bound to, reported by /api/info.
+	listenPort string
+
+	// dataDir is where writeHandler stores log files. It is resolved to an
+	// absolute path once at startup from DATA_DIR.
+	dataDir = "./data/log"
 )
 
 type AppInfo struct {
-	AppName   string    `json:"app_name"`
-	Env       string    `json:"environment"`
-	DBUser    string    `json:"db_user"`
-	Version   string    `json:"version"`
-	Hostname  string    `json:"hostname"`
-	Timestamp time.Time `json:"timestamp"`
+	AppName        string            `json:"app_name"`
+	Env            string            `json:"environment"`
+	DBUser         string            `json:"db_user"`
+	Version        string            `json:"version"`
+	GitCommit      string            `json:"git_commit"`
+	BuildTime      string            `json:"build_time"`
+	Hostname       string            `json:"hostname"`
+	Port           string            `json:"port"`
+	PodName        string            `json:"pod_name,omitempty"`
+	Namespace      string            `json:"namespace,omitempty"`
+	PodIP          string            `json:"pod_ip,omitempty"`
+	NodeName       string            `json:"node_name,omitempty"`
+	Features       []string          `json:"features"`
+	ConfigSnapshot map[string]string `json:"config_snapshot"`
+	Timestamp      time.Time         `json:"timestamp"`
 }
 
 type Stats struct {
-	Uptime         string `json:"uptime"`
-	TotalRequests  int64  `json:"total_requests"`
-	WriteOps       int64  `json:"write_operations"`
-	GoVersion      string `json:"go_version"`
-	NumGoroutines  int    `json:"goroutines"`
-	MemoryAllocMB  uint64 `json:"memory_alloc_mb"`
-	ServerTime     string `json:"server_time"`
+	Uptime           string                `json:"uptime"`
+	TotalRequests    int64                 `json:"total_requests"`
+	WriteOps         int64                 `json:"write_operations"`
+	DeleteOps        int64                 `json:"delete_operations"`
+	FilesCleaned     int64                 `json:"files_cleaned"`
+	Panics           int64                 `json:"panics"`
+	GoVersion        string                `json:"go_version"`
+	NumCPU           int                   `json:"num_cpu"`
+	NumGoroutines    int                   `json:"goroutines"`
+	MemoryAllocMB    uint64                `json:"memory_alloc_mb"`
+	HeapObjects      uint64                `json:"heap_objects"`
+	NextGCMB         uint64                `json:"next_gc_mb"`
+	NumGC            uint32                `json:"gc_count"`
+	GCPauseTotalMs   float64               `json:"gc_pause_total_ms"`
+	OpenFDs          int                   `json:"open_fds"`
+	ServerTime       string                `json:"server_time"`
+	RequestsByPath   map[string]int64      `json:"requests_by_path"`
+	RequestsByStatus map[string]int64      `json:"requests_by_status"`
+	Routes           map[string]RouteStats `json:"routes"`
+	LogLevel         string                `json:"log_level"`
+	ShuttingDown     bool                  `json:"shutting_down"`
+	InFlight         int64                 `json:"inflight_requests"`
+	InFlightRejected int64                 `json:"inflight_rejected"`
+	OversizedBodies  int64                 `json:"oversized_bodies"`
+	RequestsPerSec   float64               `json:"requests_per_second"`
+	ErrorResponses   int64                 `json:"error_responses"`
+	ErrorRate        float64               `json:"error_rate"`
+	DiskUsage        *DiskUsage            `json:"disk_usage,omitempty"`
+	Build            VersionInfo           `json:"build"`
 }
 
-func infoHandler(w http.ResponseWriter, r *http.Request) {
-	atomic.AddInt64(&requestCount, 1)
-	logger.Printf("[INFO] 📊 Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+// newInfoHandler serves /api/info from the settings snapshot current returns.
+// The body is cached for INFO_CACHE_TTL_SEC; X-Cache says whether this
+// response came from the cache.
+func newInfoHandler(current settingsSource) http.HandlerFunc {
+	var cache appInfoCache
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordRequest(r)
+		log := requestLogger(r)
+		log.Info("📊 Request received", "method", r.Method, "path", r.URL.Path, "remote_addr", clientIP(r))
+		if !requireMethod(w, r, http.MethodGet) {
+			return
+		}
 
-	hostname, err := os.Hostname()
-	if err != nil {
-		logger.Printf("[WARN] ⚠️ Failed to get hostname: %v", err)
-		hostname = "unknown"
-	}
-	
-	info := AppInfo{
-		AppName:   getEnvOrDefault("APP_NAME", "OpenShift Go Monolith"),
-		Env:       getEnvOrDefault("APP_ENV", "development"),
-		DBUser:    getEnvOrDefault("DB_USER", "not_configured"),
-		Version:   "1.1.0",
-		Hostname:  hostname,
-		Timestamp: time.Now(),
-	}
-
-	logger.Printf("[INFO] 📤 Sending app info response: AppName=%s, Env=%s, Hostname=%s", 
-		info.AppName, info.Env, info.Hostname)
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(info); err != nil {
-		logger.Printf("[ERROR] 💥 Failed to encode JSON response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-	
-	logger.Printf("[INFO] ✅ App info request completed successfully - hits different!")
+		cfg := current()
+		info, hit := cache.get(cfg, func() AppInfo {
+			hostname, err := os.Hostname()
+			if err != nil {
+				log.Warn("⚠️ Failed to get hostname", "error", err)
+				hostname = "unknown"
+			}
+			return AppInfo{
+				AppName:        cfg.AppName,
+				Env:            cfg.Env,
+				DBUser:         cfg.DBUser,
+				Version:        Version,
+				GitCommit:      GitCommit,
+				BuildTime:      BuildTime,
+				Hostname:       hostname,
+				Port:           cfg.Port,
+				PodName:        cfg.PodName,
+				Namespace:      cfg.Namespace,
+				PodIP:          cfg.PodIP,
+				NodeName:       cfg.NodeName,
+				Features:       cfg.Features.names(),
+				ConfigSnapshot: envSnapshot(cfg.InfoEnvPrefixes),
+			}
+		})
+		info.Timestamp = time.Now()
+		if hit {
+			w.Header().Set("X-Cache", "HIT")
+		} else {
+			w.Header().Set("X-Cache", "MISS")
+		}
+
+		log.Info("📤 Sending app info response",
+			"app_name", info.AppName, "environment", info.Env, "hostname", info.Hostname)
+
+		if err := respond(w, r, info); err != nil {
+			log.Error("💥 Failed to encode app info response", "error", err)
+			return
+		}
+
+		log.Info("✅ App info request completed successfully - hits different!")
+	}
 }
 
-func writeHandler(w http.ResponseWriter, r *http.Request) {
-	atomic.AddInt64(&requestCount, 1)
-	atomic.AddInt64(&writeCount, 1)
-	
-	logger.Printf("[INFO] 📝 Write request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
-	
-	// Create log directory if it doesn't exist
-	logDir := "./data/log"
-	logger.Printf("[DEBUG] 🔍 Ensuring log directory exists: %s", logDir)
-	
-	if err := os.MkdirAll(logDir, 0755); err != nil {
-		logger.Printf("[ERROR] 🚨 Failed to create log directory %s: %v", logDir, err)
-		http.Error(w, fmt.Sprintf("Failed to create log directory: %v", err), http.StatusInternalServerError)
-		return
-	}
-	logger.Printf("[DEBUG] ✅ Log directory ready: %s", logDir)
-
-	// Create timestamped log file
-	timestamp := time.Now().Format("20060102-150405")
-	filename := fmt.Sprintf("%s-log.txt", timestamp)
-	filepath := filepath.Join(logDir, filename)
-	
-	logger.Printf("[INFO] 📄 Creating log file: %s", filepath)
-
-	f, err := os.OpenFile(filepath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		logger.Printf("[ERROR] 💥 Failed to create log file %s: %v", filepath, err)
-		http.Error(w, fmt.Sprintf("Failed to create log file: %v", err), http.StatusInternalServerError)
-		return
+// WriteResult is the JSON answer to /api/write.
+type WriteResult struct {
+	Filename        string    `json:"filename"`
+	Operation       int64     `json:"operation"`
+	Sequence        int64     `json:"sequence"`
+	Timestamp       time.Time `json:"timestamp"`
+	SizeBytes       int       `json:"size_bytes"`
+	CompressedBytes int       `json:"compressed_bytes,omitempty"`
+	LogDir          string    `json:"log_dir"`
+}
+
+// newWriteHandler persists one log file per request under the data directory
+// from the settings snapshot current returns.
+func newWriteHandler(current settingsSource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordRequest(r)
+		log := requestLogger(r)
+		cfg := current()
+
+		log.Info("📝 Write request received", "method", r.Method, "path", r.URL.Path, "remote_addr", clientIP(r))
+		if !requireMethod(w, r, http.MethodPost) {
+			return
+		}
+		if cfg.Features[featureDisableWrite] {
+			log.Warn("🚫 Write rejected - disabled by feature flag", "feature", featureDisableWrite)
+			writeFeatureDisabled(w, featureDisableWrite, "Writes are disabled on this instance")
+			return
+		}
+
+		// Read the client payload up front so bad or oversized bodies are
+		// rejected before anything touches the volume. A JSON body carries a
+		// short payload embedded in the template; any other body is persisted
+		// as-is.
+		var body []byte
+		var payload string
+		if isJSONRequest(r) {
+			p, status, err := decodeWritePayload(w, r)
+			if err != nil {
+				if status == http.StatusRequestEntityTooLarge {
+					atomic.AddInt64(&oversizedBodies, 1)
+				}
+				log.Warn("🙅 Rejected write payload", "error", err, "status_code", status,
+					"content_length", r.ContentLength, "remote_addr", clientIP(r))
+				writeError(w, status, http.StatusText(status), err.Error())
+				return
+			}
+			payload = p
+		} else {
+			maxWriteBytes := cfg.MaxWriteBytes
+			b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWriteBytes))
+			if err != nil {
+				var maxErr *http.MaxBytesError
+				if errors.As(err, &maxErr) {
+					atomic.AddInt64(&oversizedBodies, 1)
+					log.Warn("🐘 Write payload too large", "limit_bytes", maxErr.Limit,
+						"content_length", r.ContentLength, "remote_addr", clientIP(r))
+					writeError(w, http.StatusRequestEntityTooLarge, "Payload too large",
+						fmt.Sprintf("Payload exceeds %d bytes", maxErr.Limit))
+					return
+				}
+				log.Error("💥 Failed to read write payload", "error", err)
+				writeError(w, http.StatusBadRequest, "Failed to read request body", err.Error())
+				return
+			}
+			body = b
+		}
+
+		// ?compress=true stores the file gzipped as .txt.gz
+		compress, _ := strconv.ParseBool(r.URL.Query().Get("compress"))
+
+		// Every write gets its own operation number up front; writeCount
+		// only moves once the file has landed.
+		logDir := cfg.DataDir
+		op := atomic.AddInt64(&writeOpCount, 1)
+		seq, err := writeSeq.next()
+		if err != nil {
+			log.Error("😱 Failed to reserve write sequence number", "error", err)
+			writeError(w, http.StatusInternalServerError, "Failed to reserve sequence number", err.Error())
+			return
+		}
+
+		// Create the log file, named by the sequence number so writes in the
+		// same second never replace each other
+		filename := logFileName(time.Now(), seq)
+		if compress {
+			filename += gzipLogSuffix
+		}
+		logPath := filepath.Join(logDir, filename)
+
+		log.Info("📄 Creating log file", "file", logPath)
+
+		// Client-supplied content wins; an empty body gets the canned template,
+		// or the plain one under the plain_logs feature flag
+		logContent := string(body)
+		switch {
+		case len(body) > 0:
+			log.Debug("📦 Persisting client payload", "bytes", len(body))
+		case cfg.Features[featurePlainLogs]:
+			logContent = renderPlainWriteLog(r, cfg, op, seq, payload)
+		default:
+			logContent = renderWriteLog(r, cfg, op, seq, payload)
+		}
+
+		data := []byte(logContent)
+		if compress {
+			compressed, err := gzipBytes(data)
+			if err != nil {
+				log.Error("😱 Failed to compress log content", "file", logPath, "error", err)
+				writeError(w, http.StatusInternalServerError, "Failed to compress log content", err.Error())
+				return
+			}
+			data = compressed
+		}
+
+		log.Debug("💾 Writing log content", "file", logPath, "bytes", len(logContent), "stored_bytes", len(data))
+
+		if err := writeLogFileCtx(r.Context(), logDir, logPath, data); err != nil {
+			if r.Context().Err() != nil {
+				log.Warn("🏃 Client disconnected - write abandoned", "file", logPath, "error", err)
+				w.WriteHeader(statusClientClosedRequest)
+				return
+			}
+			log.Error("😱 Failed to write content to log file", "file", logPath, "error", err)
+			writeError(w, http.StatusInternalServerError, "Failed to write log content", err.Error())
+			return
+		}
+		recordWrite()
+
+		log.Info("🎉 Successfully wrote log file - it's giving main character energy!", "file", logPath)
+
+		result := WriteResult{
+			Filename:  filename,
+			Operation: op,
+			Sequence:  seq,
+			Timestamp: time.Now(),
+			SizeBytes: len(logContent),
+			LogDir:    logDir,
+		}
+		if compress {
+			result.CompressedBytes = len(data)
+		}
+
+		log.Info("✨ Write operation completed successfully - we're so back!")
+		w.Header().Add("Vary", "Accept")
+		if negotiateContentType(r, []string{mimeJSON, mimeText}) == mimeJSON {
+			w.Header().Set("Content-Type", mimeJSON)
+			if err := json.NewEncoder(w).Encode(result); err != nil {
+				log.Error("😱 Failed to encode write result JSON", "error", err)
+			}
+			return
+		}
+
+		var compressedLine string
+		if compress {
+			compressedLine = fmt.Sprintf("🗜️ Compressed: %d bytes (gzip)\n", result.CompressedBytes)
+		}
+
+		response := fmt.Sprintf(`✓ Data written to volume successfully
+
+📁 File: %s
+🔢 Operation: #%d
+🧮 Sequence: #%d
+⏰ Timestamp: %s
+📏 Size: %d bytes
+%s
+📂 Log directory: %s
+
+💯 Status: Absolutely fire! No printer, just facts! 🔥`,
+			result.Filename,
+			result.Operation,
+			result.Sequence,
+			result.Timestamp.Format(time.RFC3339),
+			result.SizeBytes,
+			compressedLine,
+			result.LogDir)
+
+		w.Header().Set("Content-Type", mimeText+"; charset=utf-8")
+		w.Write([]byte(response))
 	}
-	defer f.Close()
+}
 
-	// Write detailed log content with Gen Z vibes
+// renderWriteLog builds the default log file body (with Gen Z vibes) used
+// when a write request carries no raw body. A non-empty payload from a JSON
+// request gets its own User Payload section. op counts writes since the
+// counters were last reset; seq is the durable sequence number.
+func renderWriteLog(r *http.Request, cfg *runtimeSettings, op, seq int64, payload string) string {
 	hostname, _ := os.Hostname()
-	appName := getEnvOrDefault("APP_NAME", "OpenShift Go Monolith")
-	env := getEnvOrDefault("APP_ENV", "development")
-	
-	logContent := fmt.Sprintf(`========================================
+	appName := cfg.AppName
+	env := cfg.Env
+
+	payloadSection := ""
+	if payload != "" {
+		payloadSection = fmt.Sprintf(`
+🧾 User Payload:
+%s
+`, payload)
+	}
+
+	return fmt.Sprintf(`========================================
 🚀 OpenShift Go Monolith - Volume Write Log
 ========================================
 
 ⏰ Timestamp:        %s
 🔢 Operation Number: %d
+🧮 Sequence Number:  %d
 📦 Application:      %s
 🌍 Environment:      %s
 🏠 Hostname:         %s
@@ -141,9 +392,9 @@ No cap, this is bussin fr fr! 💯
 - Path: %s
 - User Agent: %s
 - Remote Address: %s
-
+%s
 💭 Vibes: Immaculate ✨
-🎯 Status: Mission accomplished, chief! 
+🎯 Status: Mission accomplished, chief!
 🔥 Performance: Absolutely slaying rn
 
 ========================================
@@ -151,85 +402,97 @@ No cap, this is bussin fr fr! 💯
 ========================================
 `,
 		time.Now().Format(time.RFC3339),
-		atomic.LoadInt64(&writeCount),
+		op,
+		seq,
 		appName,
 		env,
 		hostname,
-		r.RemoteAddr,
+		clientIP(r),
 		runtime.Version(),
 		atomic.LoadInt64(&requestCount),
 		time.Since(startTime).Round(time.Second).String(),
-		atomic.LoadInt64(&writeCount),
+		op,
 		runtime.NumGoroutine(),
 		getMemoryUsageMB(),
 		r.Method,
 		r.URL.Path,
 		r.UserAgent(),
-		r.RemoteAddr,
+		clientIP(r),
+		payloadSection,
 	)
+}
 
-	logger.Printf("[DEBUG] 💾 Writing %d bytes to log file", len(logContent))
-	
-	if _, err := f.WriteString(logContent); err != nil {
-		logger.Printf("[ERROR] 😱 Failed to write content to log file %s: %v", filepath, err)
-		http.Error(w, fmt.Sprintf("Failed to write log content: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	logger.Printf("[INFO] 🎉 Successfully wrote log file: %s - it's giving main character energy!", filepath)
+// statusClientClosedRequest is logged for requests abandoned because the
+// client went away (nginx's convention; nobody receives it).
+const statusClientClosedRequest = 499
 
-	response := fmt.Sprintf(`✓ Data written to volume successfully
+// maxPayloadBytes caps the JSON body accepted by /api/write.
+const maxPayloadBytes = 4 << 10
 
-📁 File: %s
-🔢 Operation: #%d
-⏰ Timestamp: %s
-📏 Size: %d bytes
-
-📂 Log directory: %s
+// WritePayload is the optional JSON body for /api/write.
+type WritePayload struct {
+	Payload string `json:"payload"`
+}
 
-💯 Status: Absolutely fire! No printer, just facts! 🔥`, 
-		filename,
-		atomic.LoadInt64(&writeCount),
-		time.Now().Format(time.RFC3339),
-		len(logContent),
-		logDir)
-	
-	logger.Printf("[INFO] ✨ Write operation completed successfully - we're so back!")
-	w.Write([]byte(response))
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/json"
 }
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	atomic.AddInt64(&requestCount, 1)
-	logger.Printf("[INFO] ❤️ Health check request from %s - checking the vibes...", r.RemoteAddr)
-	w.Write([]byte("OK"))
-	logger.Printf("[DEBUG] 💚 Health check response sent - we're thriving!")
+// decodeWritePayload reads a WritePayload from r, limited to
+// maxPayloadBytes. An empty body yields an empty payload. On failure it
+// returns the HTTP status to answer with.
+func decodeWritePayload(w http.ResponseWriter, r *http.Request) (string, int, error) {
+	var req WritePayload
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPayloadBytes)).Decode(&req)
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, io.EOF):
+		return "", http.StatusOK, nil
+	case errors.As(err, &maxErr):
+		return "", http.StatusRequestEntityTooLarge, fmt.Errorf("payload exceeds %d bytes", maxErr.Limit)
+	case err != nil:
+		return "", http.StatusBadRequest, fmt.Errorf("malformed JSON body: %v", err)
+	}
+	return req.Payload, http.StatusOK, nil
 }
 
-func statsHandler(w http.ResponseWriter, r *http.Request) {
-	atomic.AddInt64(&requestCount, 1)
-	logger.Printf("[INFO] 📈 Stats request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+// newStatsHandler serves /api/stats for the settings snapshot current returns.
+func newStatsHandler(current settingsSource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordRequest(r)
+		log := requestLogger(r)
+		log.Info("📈 Stats request received", "method", r.Method, "path", r.URL.Path, "remote_addr", clientIP(r))
+		if !requireMethod(w, r, http.MethodGet) {
+			return
+		}
 
-	stats := Stats{
-		Uptime:         time.Since(startTime).Round(time.Second).String(),
-		TotalRequests:  atomic.LoadInt64(&requestCount),
-		WriteOps:       atomic.LoadInt64(&writeCount),
-		GoVersion:      runtime.Version(),
-		NumGoroutines:  runtime.NumGoroutine(),
-		MemoryAllocMB:  getMemoryUsageMB(),
-		ServerTime:     time.Now().Format(time.RFC3339),
-	}
+		stats := collectStats(current(), log)
+
+		log.Debug("📊 Stats collected - looking good!",
+			"uptime", stats.Uptime, "total_requests", stats.TotalRequests,
+			"write_operations", stats.WriteOps, "memory_alloc_mb", stats.MemoryAllocMB)
+
+		if err := respond(w, r, stats); err != nil {
+			log.Error("😱 Failed to encode stats response", "error", err)
+			return
+		}
 
-	logger.Printf("[DEBUG] 📊 Stats collected: Uptime=%s, Requests=%d, WriteOps=%d, Memory=%dMB - looking good!", 
-		stats.Uptime, stats.TotalRequests, stats.WriteOps, stats.MemoryAllocMB)
+		log.Info("✨ Stats request completed successfully - data is immaculate!")
+	}
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(stats); err != nil {
-		logger.Printf("[ERROR] 😱 Failed to encode stats JSON: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+// requireMethod answers 405 with an Allow header unless r uses one of the
+// allowed methods, and reports whether the handler should carry on.
+func requireMethod(w http.ResponseWriter, r *http.Request, allowed ...string) bool {
+	if slices.Contains(allowed, r.Method) {
+		return true
 	}
-	
-	logger.Printf("[INFO] ✨ Stats request completed successfully - data is immaculate!")
+	requestLogger(r).Warn("🚫 Method not allowed - wrong vibe", "method", r.Method, "path", r.URL.Path, "allowed", allowed)
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "Method not allowed",
+		fmt.Sprintf("%s is not supported here; use %s", r.Method, strings.Join(allowed, " or ")))
+	return false
 }
 
 func getMemoryUsageMB() uint64 {
@@ -238,100 +501,578 @@ func getMemoryUsageMB() uint64 {
 	return m.Alloc / 1024 / 1024
 }
 
+// getEnvOrDefault returns the environment variable key, then the matching
+// CONFIG_FILE value, then defaultValue.
 func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+	if value := lookupSetting(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
 
-func loggingMiddleware(next http.Handler) http.Handler {
+// getDurationOrDefault parses key as a time.Duration (e.g. "15s"), falling
+// back to defaultValue when the variable is unset or malformed.
+func getDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	raw := lookupSetting(key)
+	if raw == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		logger.Warn("⚠️ Invalid duration, using default", "key", key, "value", raw, "default", defaultValue.String())
+		return defaultValue
+	}
+	return d
+}
+
+// getIntOrDefault parses key as a positive integer, falling back to
+// defaultValue when the variable is unset or malformed.
+func getIntOrDefault(key string, defaultValue int) int {
+	raw := lookupSetting(key)
+	if raw == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		logger.Warn("⚠️ Invalid integer, using default", "key", key, "value", raw, "default", defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// responseWriter wraps http.ResponseWriter so the middleware can see which
+// status code the handler sent and how many body bytes went out. The status
+// defaults to 200 for handlers that never call WriteHeader.
+type responseWriter struct {
+	http.ResponseWriter
+	status       int
+	bytesWritten int
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	rw.status = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytesWritten += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the connection underneath.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Push passes HTTP/2 server push through to the connection underneath.
+func (rw *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := rw.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// loggingMiddleware logs one line per completed request and feeds the
+// latency histogram and the per-route counters. routes resolves each request
+// to the mux pattern it matched. While draining, responses carry
+// Connection: close so keep-alive clients reconnect elsewhere.
+func loggingMiddleware(routes *http.ServeMux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			route := routeFor(routes, r)
+			if draining.Load() {
+				w.Header().Set("Connection", "close")
+			}
+
+			next.ServeHTTP(rw, r)
+
+			duration := time.Since(start)
+			requestDuration.WithLabelValues(r.Method).Observe(duration.Seconds())
+			traffic.record(route, rw.status)
+			recordRouteLatency(r.Method, route, duration)
+			atomic.AddInt64(&responseCount, 1)
+			if rw.status >= 400 {
+				atomic.AddInt64(&errorResponseCount, 1)
+			}
+			requestLogger(r).Info("⚡ Request completed - speedrun any%",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"route", route,
+				"remote_addr", clientIP(r),
+				"user_agent", r.UserAgent(),
+				"proto", r.Proto,
+				"status_code", rw.status,
+				"bytes_written", rw.bytesWritten,
+				"duration_ms", float64(duration.Microseconds())/1000,
+			)
+		})
+	}
+}
+
+// recoverMiddleware turns a handler panic into a logged stack trace and a
+// JSON 500 instead of a dropped connection.
+func recoverMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		logger.Printf("[REQUEST] 🌐 %s %s from %s - User-Agent: %s", 
-			r.Method, r.URL.Path, r.RemoteAddr, r.UserAgent())
-		
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				atomic.AddInt64(&panicCount, 1)
+				requestLogger(r).Error("💀 Handler panicked - not very demure",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"panic", fmt.Sprint(rec),
+					"stack", string(debug.Stack()),
+				)
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"error":"internal server error"}`))
+			}
+		}()
 		next.ServeHTTP(w, r)
-		
-		duration := time.Since(start)
-		logger.Printf("[RESPONSE] ⚡ %s %s completed in %v - speedrun any%%", r.Method, r.URL.Path, duration)
 	})
 }
 
-func initLogger() {
-	logger = log.New(os.Stdout, "", log.LstdFlags|log.Lmicroseconds)
-	logger.SetFlags(log.LstdFlags | log.Lmicroseconds | log.Lshortfile)
-	logger.Println("[INIT] 🎯 Logger initialized with detailed output - let's get this bread!")
+// initLogger sets up the package-level structured logger, writing to out. Output is JSON by
+// default so log aggregators can index the fields; LOG_FORMAT=text switches
+// to slog's key=value format for local development. APP_LOG_LEVEL (debug,
+// info, warn, error) sets the threshold; see defaultLogLevel for the default.
+func initLogger(out io.Writer) {
+	fallback := defaultLogLevel(getEnvOrDefault("APP_ENV", "development"))
+	rawLevel := getEnvOrDefault("APP_LOG_LEVEL", fallback.String())
+	levelErr := logLevel.UnmarshalText([]byte(rawLevel))
+	if levelErr != nil {
+		logLevel.Set(fallback)
+	}
+
+	opts := &slog.HandlerOptions{
+		Level: logLevel,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				a.Key = "timestamp"
+			}
+			return a
+		},
+	}
+
+	var handler slog.Handler
+	format := getEnvOrDefault("APP_LOG_FORMAT", "json")
+	switch strings.ToLower(format) {
+	case "text":
+		handler = slog.NewTextHandler(out, opts)
+	default:
+		handler = slog.NewJSONHandler(out, opts)
+	}
+
+	logger = slog.New(handler)
+	if levelErr != nil {
+		logger.Warn("⚠️ Invalid APP_LOG_LEVEL, using default", "value", rawLevel, "default", fallback.String())
+	}
+	logger.Info("🎯 Logger initialized - let's get this bread!",
+		"format", format, "level", logLevel.Level().String())
+}
+
+// defaultLogLevel keeps production quiet while leaving per-request debug
+// lines on everywhere else.
+func defaultLogLevel(appEnv string) slog.Level {
+	if appEnv == "production" {
+		return slog.LevelInfo
+	}
+	return slog.LevelDebug
 }
 
 func main() {
-	// Initialize logger first
-	initLogger()
-	
-	// Load .env file
-	if err := godotenv.Load(); err != nil {
-		logger.Printf("[WARN] ⚠️ No .env file found or error loading it: %v", err)
-		logger.Println("[INFO] 📝 Using system environment variables or defaults")
-	} else {
-		logger.Println("[INFO] ✅ Successfully loaded .env file")
-	}
-	
-	logger.Println("========================================")
-	logger.Println("🚀 OpenShift Go Monolith Server")
-	logger.Println("========================================")
-	logger.Printf("[INIT] 💫 Version: 1.1.0")
-	logger.Printf("[INIT] 🐹 Go Version: %s", runtime.Version())
-	logger.Printf("[INIT] 💻 OS/Arch: %s/%s", runtime.GOOS, runtime.GOARCH)
-	logger.Printf("[INIT] ⚡ CPUs: %d", runtime.NumCPU())
-	logger.Printf("[INIT] ⏰ Started at: %s", time.Now().Format(time.RFC3339))
-	
+	flags := parseFlags()
+
+	// Load .env and the config file before the logger so APP_LOG_FORMAT can
+	// come from either
+	envFiles := loadEnvFiles(flags.envFile)
+	configFile := os.Getenv("CONFIG_FILE")
+	cfg, cfgErr := loadConfigFile(configFile)
+	fileConfig = cfg
+
+	// A dry run keeps stdout for its summary
+	validateOnly := flags.validateOnly()
+	logOut := io.Writer(os.Stdout)
+	if validateOnly {
+		logOut = os.Stderr
+	}
+	// Everything logged is also kept for /api/applogs
+	ringSize, badRingSize := logRingSize()
+	appLogs = newLogRing(ringSize)
+	initLogger(io.MultiWriter(logOut, appLogs))
+	if badRingSize != "" {
+		logger.Warn("⚠️ Invalid integer, using default", "key", "LOG_RING_BUFFER_SIZE", "value", badRingSize, "default", defaultLogRingSize)
+	}
+
+	// Problems are collected rather than fatal straight away so every one
+	// is reported in a single pass
+	var problems []string
+	if cfgErr != nil {
+		logger.Error("💀 Invalid config file", "file", configFile, "error", cfgErr)
+		problems = append(problems, fmt.Sprintf("config file %s: %v", configFile, cfgErr))
+	}
+	if configFile != "" {
+		logger.Info("✅ Loaded config file", "file", configFile)
+	}
+
+	var loadedEnvFiles []string
+	for _, ef := range envFiles {
+		if ef.err != nil {
+			logger.Warn("⚠️ Env file not found or unreadable", "file", ef.path, "error", ef.err)
+			continue
+		}
+		loadedEnvFiles = append(loadedEnvFiles, ef.path)
+		logger.Info("✅ Loaded env file", "file", ef.path, "keys", ef.keys)
+	}
+	if len(loadedEnvFiles) == 0 {
+		logger.Info("📝 Using system environment variables or defaults")
+	}
+	if strictEnvMode() {
+		if missing := missingRequiredEnv(); len(missing) > 0 {
+			for _, key := range missing {
+				logger.Error("💀 Required env var not set", "key", key)
+				problems = append(problems, fmt.Sprintf("required env var %s is not set", key))
+			}
+		}
+	}
+	for legacy, key := range deprecatedEnvKeys() {
+		logger.Warn("[CONFIG] ⚠️ Deprecated env var, please rename it - this is giving legacy", "old", legacy, "new", key)
+	}
+
+	logger.Info("🚀 OpenShift Go Monolith Server",
+		"version", Version,
+		"git_commit", GitCommit,
+		"build_time", BuildTime,
+		"go_version", runtime.Version(),
+		"os", runtime.GOOS,
+		"arch", runtime.GOARCH,
+		"cpus", runtime.NumCPU(),
+		"started_at", time.Now().Format(time.RFC3339),
+	)
+
 	// Log environment variables
-	logger.Printf("[CONFIG] 📦 APP_NAME: %s", getEnvOrDefault("APP_NAME", "not set"))
-	logger.Printf("[CONFIG] 🌍 APP_ENV: %s", getEnvOrDefault("APP_ENV", "not set"))
-	logger.Printf("[CONFIG] 👤 DB_USER: %s", getEnvOrDefault("DB_USER", "not set"))
-	
+	logConfig("📦 APP_NAME", "APP_NAME", getEnvOrDefault("APP_NAME", "not set"), envSource("APP_NAME"))
+	logConfig("🌍 APP_ENV", "APP_ENV", getEnvOrDefault("APP_ENV", "not set"), envSource("APP_ENV"))
+	logConfig("👤 APP_DB_USER", "APP_DB_USER", getEnvOrDefault("APP_DB_USER", "not set"), envSource("APP_DB_USER"))
+
 	hostname, err := os.Hostname()
 	if err != nil {
-		logger.Printf("[WARN] ⚠️ Failed to get hostname: %v", err)
+		logger.Warn("⚠️ Failed to get hostname", "error", err)
 	} else {
-		logger.Printf("[CONFIG] 🏠 Hostname: %s", hostname)
+		logConfig("🏠 Hostname", "HOSTNAME", hostname, "os")
+	}
+
+	port, portSource := flags.resolveSetting("port", flags.port, "APP_PORT", "8080")
+	addr, port := resolveListenAddr(port, portSource)
+	listenPort, listenPortSource = port, portSource
+	logConfig("🔌 Listen address", "LISTEN_ADDR", addr, portSource)
+
+	// Resolve data directory
+	dir, dirSource := flags.resolveSetting("data", flags.dataDir, "APP_DATA_DIR", dataDir)
+	dataDir, dataDirSource = resolveDataDir(dir), dirSource
+	logConfig("📂 APP_DATA_DIR", "APP_DATA_DIR", dataDir, dirSource)
+
+	// Refuse to start on bad config, reporting every problem at once
+	appEnv := getEnvOrDefault("APP_ENV", "development")
+	problems = append(problems, validateConfig(addr, port, dataDir, appEnv, validateOnly)...)
+
+	certFile, keyFile := tlsFiles()
+	if validateOnly {
+		for _, err := range []error{checkStaticDir(staticDir()), checkTLSFiles(certFile, keyFile)} {
+			if err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+		report := validationReport{
+			Valid:      len(problems) == 0,
+			Problems:   problems,
+			Build:      currentVersionInfo(),
+			AppEnv:     appEnv,
+			ListenAddr: addr,
+			DataDir:    dataDir,
+			EnvFiles:   loadedEnvFiles,
+			ConfigFile: configFile,
+			TLS:        certFile != "" && keyFile != "",
+		}
+		if err := writeValidationReport(os.Stdout, report, flags.jsonOutput); err != nil || !report.Valid {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if len(problems) > 0 {
+		logger.Error("💀 Invalid configuration - refusing to start", "violations", problems)
+		os.Exit(1)
+	}
+	logger.Info("✅ Data directory exists and is writable", "dir", dataDir)
+	tuneGOMAXPROCS()
+
+	settings.Store(loadRuntimeSettings())
+
+	removeOrphanedTempFiles(dataDir)
+
+	// Number written files durably so restarts don't reuse numbers
+	writeSeq, err = openWriteSequence(dataDir)
+	if err != nil {
+		logger.Error("💀 Unreadable write sequence file - refusing to renumber", "error", err)
+		os.Exit(1)
 	}
-	
-	// Check data directory
-	dataDir := "./data/log"
-	if _, err := os.Stat(dataDir); os.IsNotExist(err) {
-		logger.Printf("[WARN] 📁 Data directory %s does not exist, will be created on first write", dataDir)
+	logger.Info("🧮 Write sequence restored", "file", writeSeq.path, "last", writeSeq.last)
+	watchReloadSignal(flags.envFile)
+
+	_, unknownFeatures := parseFeatures(getEnvOrDefault("FEATURES", ""))
+	for _, name := range unknownFeatures {
+		logger.Warn("[CONFIG] ⚠️ Unknown feature flag ignored", "feature", name, "known", knownFeatures)
+	}
+	logger.Info("[CONFIG] 🚩 Feature flags", "enabled", settings.Load().Features.names())
+
+	// Prune old log files in the background so the volume doesn't fill up.
+	// LOG_RETENTION (a duration) overrides LOG_MAX_AGE_DAYS; 0 turns cleanup
+	// off altogether, LOG_MAX_FILES included.
+	cleanupInterval := getDurationOrDefault("CLEANUP_INTERVAL", time.Hour)
+	if cleanupInterval == 0 {
+		logger.Warn("⚠️ CLEANUP_INTERVAL must be positive, using default", "default", time.Hour.String())
+		cleanupInterval = time.Hour
+	}
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	maxAge := time.Duration(getIntOrDefault("LOG_MAX_AGE_DAYS", 7)) * 24 * time.Hour
+	retention := getDurationOrDefault("LOG_RETENTION", maxAge)
+	cleanupDone := startLogCleanup(bgCtx, dataDir, cleanupInterval, retention, getIntOrDefault("LOG_MAX_FILES", 500))
+
+	// Carry request and write counters across restarts
+	countersFile := getEnvOrDefault("COUNTERS_FILE", "./data/counters.json")
+	if err := loadCounters(countersFile); err != nil {
+		logger.Warn("⚠️ Could not restore counters, starting from zero", "file", countersFile, "error", err)
 	} else {
-		logger.Printf("[INFO] ✅ Data directory %s exists and is accessible", dataDir)
+		logger.Info("💾 Counters restored", "file", countersFile,
+			"total_requests", atomic.LoadInt64(&requestCount), "write_operations", atomic.LoadInt64(&writeCount))
+	}
+	flushInterval := time.Duration(getIntOrDefault("COUNTER_FLUSH_SEC", 10)) * time.Second
+	countersDone := startCounterFlusher(bgCtx, countersFile, flushInterval)
+
+	trustProxy, _ = strconv.ParseBool(getEnvOrDefault("TRUST_PROXY", "false"))
+	if trustProxy {
+		logger.Info("[CONFIG] 🧭 Trusting X-Forwarded-For / X-Real-IP for client IPs")
+	}
+
+	shutdownTracing, err := initTracing(bgCtx, settings.Load().AppName)
+	if err != nil {
+		logger.Error("💀 Failed to set up tracing", "error", err)
+		os.Exit(1)
 	}
-	
+
 	// Setup routes with logging middleware
-	logger.Println("[INIT] 🔧 Registering HTTP handlers...")
-	
-	mux := http.NewServeMux()
-	mux.Handle("/", http.FileServer(http.Dir("./static")))
-	mux.HandleFunc("/api/info", infoHandler)
-	mux.HandleFunc("/api/write", writeHandler)
-	mux.HandleFunc("/api/stats", statsHandler)
-	mux.HandleFunc("/health", healthHandler)
-	
-	logger.Println("[INIT] 🛣️ Routes registered:")
-	logger.Println("[INIT]   📄 GET  /              - Static files")
-	logger.Println("[INIT]   📊 GET  /api/info      - Application info")
-	logger.Println("[INIT]   💾 POST /api/write     - Write volume data")
-	logger.Println("[INIT]   📈 GET  /api/stats     - Application statistics")
-	logger.Println("[INIT]   ❤️ GET  /health        - Health check")
-	
-	// Wrap with logging middleware
-	handler := loggingMiddleware(mux)
-	
-	logger.Println("========================================")
-	logger.Printf("[INIT] 🎧 Server listening on :8080")
-	logger.Println("[INIT] ✨ Ready to accept connections - let's goooo!")
-	logger.Println("========================================")
-	
-	if err := http.ListenAndServe(":8080", handler); err != nil {
-		logger.Printf("[FATAL] 💀 Server failed to start: %v", err)
+	logger.Info("[INIT] 🔧 Registering HTTP handlers...")
+
+	// Writes touch the volume, so they get a much tighter budget than reads.
+	// Both then share one cap on concurrent requests, which the app log
+	// stream is exempt from; probes, metrics and static files skip both.
+	inflight := inflightMiddleware(getIntOrDefault("MAX_INFLIGHT", 256), getDurationOrDefault("MAX_INFLIGHT_WAIT", 100*time.Millisecond))
+	readRate := rateLimitMiddleware(func() rateLimit { return settings.Load().ReadLimit })
+	writeRate := rateLimitMiddleware(func() rateLimit { return settings.Load().WriteLimit })
+	readLimit := func(next http.Handler) http.Handler { return readRate(inflight(next)) }
+	writeLimit := func(next http.Handler) http.Handler { return writeRate(inflight(next)) }
+	auth := basicAuthMiddleware("openshift-go-monolith")
+	routes := appRoutes(settings.Load, readLimit, writeLimit, auth)
+
+	// ADMIN_PORT moves stats, config and metrics off the public port
+	adminPort := os.Getenv("ADMIN_PORT")
+	split := adminPort != ""
+	mux, publicLines := buildMux(routes, split, false)
+	logger.Info("[INIT] 🛣️ Routes registered", "port", port, "routes", publicLines)
+
+	// Wrap with logging middleware; recovery sits inside it so panics are
+	// logged as 500s, with compression in between so logged sizes are what
+	// went over the wire. The per-request timeout is innermost so a panic
+	// still reaches recovery. Oversized bodies are refused just inside
+	// logging so the 413s are logged too. The request ID is assigned before
+	// logging so every line carries it. CORS is outermost so preflights never
+	// reach the mux.
+	cors := loadCORSConfig()
+	if len(cors.origins) > 0 {
+		logger.Info("[CONFIG] 🌐 CORS enabled", "origins", cors.origins, "methods", cors.methods, "credentials", cors.credentials)
+	}
+	requestTimeouts := loadRequestTimeouts()
+	bodyLimit := bodyLimitMiddleware(int64(getIntOrDefault("MAX_BODY_BYTES", getIntOrDefault("MAX_REQUEST_BODY_BYTES", 1<<20))))
+	chain := func(mux *http.ServeMux, inner http.Handler) http.Handler {
+		return corsMiddleware(cors)(requestIDMiddleware(tracingMiddleware(mux)(loggingMiddleware(mux)(bodyLimit(inner)))))
+	}
+	wrap := func(mux *http.ServeMux) http.Handler {
+		return chain(mux, gzipMiddleware(recoverMiddleware(requestTimeouts.middleware(mux)(mux))))
+	}
+
+	server := &http.Server{
+		Addr:    addr,
+		Handler: wrap(mux),
+	}
+	servers := []*http.Server{server}
+
+	// Side servers are plain-HTTP servers on their own ports next to the main one
+	type sideServer struct {
+		name   string
+		server *http.Server
+		ln     net.Listener
+	}
+	var sides []*sideServer
+	if split {
+		adminMux, adminLines := buildMux(routes, split, true)
+		logger.Info("[INIT] 🛡️ Admin routes registered", "port", adminPort, "routes", adminLines)
+		sides = append(sides, &sideServer{name: "admin", server: &http.Server{
+			Addr:    net.JoinHostPort(os.Getenv("BIND_ADDR"), adminPort),
+			Handler: wrap(adminMux),
+		}})
+	}
+	if pprofEnabled() {
+		debugMux, debugLines := buildMux(debugRoutes(auth), false, false)
+		logger.Warn("[CONFIG] 🔬 pprof enabled on the debug port - remember to turn it off", "port", debugPort(), "routes", debugLines)
+		// No per-request timeout here: a CPU profile takes 30s by default,
+		// and pprof pushes the write deadline out by the profile's length.
+		// No gzip either: profiles are gzipped protobuf already
+		sides = append(sides, &sideServer{name: "debug", server: &http.Server{
+			Addr:    net.JoinHostPort(os.Getenv("BIND_ADDR"), debugPort()),
+			Handler: chain(debugMux, recoverMiddleware(debugMux)),
+		}})
+	}
+	shutdownTimeout := resolveShutdownTimeout()
+	drainDelay := resolveDrainDelay()
+
+	// Serve HTTPS when both certificate files are configured
+	useTLS := certFile != "" && keyFile != ""
+	if useTLS {
+		reloader, err := newCertReloader(certFile, keyFile)
+		if err != nil {
+			logger.Error("💀 Invalid TLS configuration", "error", err)
+			os.Exit(1)
+		}
+		server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+		go reloader.watch(certReloadInterval)
+		logger.Info("[CONFIG] 🔐 TLS enabled", "cert_file", certFile, "key_file", keyFile, "min_version", "1.2")
+
+		if redirectPort := os.Getenv("TLS_REDIRECT_PORT"); redirectPort != "" {
+			sides = append(sides, &sideServer{name: "redirect", server: &http.Server{
+				Addr:    net.JoinHostPort(os.Getenv("BIND_ADDR"), redirectPort),
+				Handler: newRedirectHandler(port),
+			}})
+		}
+	} else {
+		logger.Info("[CONFIG] 🔓 TLS off - serving plain HTTP, terminate TLS at the router")
+	}
+	server.Handler = withH2C(server.Handler, useTLS)
+	for _, side := range sides {
+		servers = append(servers, side.server)
+	}
+	timeouts := loadServerTimeouts()
+	connOptions = loadServerConnOptions()
+	for _, srv := range servers {
+		timeouts.apply(srv)
+		connOptions.apply(srv)
+		srv.RegisterOnShutdown(appLogs.close)
+	}
+
+	// Bind up front so a taken port or bad address fails startup with the
+	// address in the message. TCP is the default; LISTEN_SOCKET adds a Unix
+	// socket, and replaces TCP unless a TCP address was configured too.
+	socketPath := os.Getenv("LISTEN_SOCKET")
+	tcpConfigured := portSource != sourceDefault || os.Getenv("LISTEN_ADDR") != "" || os.Getenv("BIND_ADDR") != ""
+
+	var listeners []net.Listener
+	if socketPath == "" || tcpConfigured {
+		ln, err := net.Listen("tcp", server.Addr)
+		if err != nil {
+			logger.Error("💀 Failed to bind listen address", "addr", server.Addr, "error", err)
+			os.Exit(1)
+		}
+		listeners = append(listeners, ln)
+	}
+	if socketPath != "" {
+		ln, err := listenUnixSocket(socketPath)
+		if err != nil {
+			logger.Error("💀 Failed to listen on socket", "socket", socketPath, "error", err)
+			os.Exit(1)
+		}
+		listeners = append(listeners, ln)
+	}
+
+	for _, side := range sides {
+		side.ln, err = net.Listen("tcp", side.server.Addr)
+		if err != nil {
+			logger.Error("💀 Failed to bind "+side.name+" address", "addr", side.server.Addr, "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Warm up alongside serving; /healthz/startup reports on it
+	go startup.run(startupTasks(settings.Load()))
+
+	serverErr := make(chan error, len(listeners)+len(sides))
+	for _, side := range sides {
+		go func() {
+			logger.Info("[INIT] 🛡️ Side server listening", "server", side.name, "addr", side.ln.Addr().String())
+			if err := side.server.Serve(side.ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serverErr <- fmt.Errorf("serve %s %s: %w", side.name, side.ln.Addr(), err)
+			}
+		}()
+	}
+	for _, ln := range listeners {
+		go func() {
+			logger.Info("[INIT] 🎧 Server listening - let's goooo!",
+				"network", ln.Addr().Network(), "addr", ln.Addr().String(), "tls", useTLS)
+			var err error
+			if useTLS {
+				err = server.ServeTLS(ln, "", "")
+			} else {
+				err = server.Serve(ln)
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serverErr <- fmt.Errorf("serve %s: %w", ln.Addr(), err)
+			}
+		}()
+	}
+
+	sigCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+
+	var reason string
+	select {
+	case err := <-serverErr:
+		logger.Error("💀 Server failed to start", "error", err)
 		os.Exit(1)
+	case <-sigCtx.Done():
+		// Newer toolchains name the signal in the cancellation cause
+		reason = "shutdown signal received"
+		if cause := context.Cause(sigCtx); cause != context.Canceled {
+			reason = cause.Error()
+		}
+	case reason = <-shutdownRequests:
 	}
-}
\ No newline at end of file
+	// A second signal now kills the process instead of waiting on the drain
+	stopSignals()
+	logger.Info("[SHUTDOWN] 📡 Shutdown signal received", "reason", reason)
+
+	preShutdownDrain(drainDelay, reason)
+	err = gracefulShutdown(servers, reason, shutdownTimeout)
+	if socketPath != "" {
+		removeSocket(socketPath)
+	}
+	stopBackground()
+	<-cleanupDone
+	<-countersDone
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	if flushErr := shutdownTracing(flushCtx); flushErr != nil {
+		logger.Warn("[SHUTDOWN] ⚠️ Failed to flush trace spans", "error", flushErr)
+	}
+	cancelFlush()
+	if err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestMain gives the package a logger, which main normally sets up, so the
+// code under test can log without a nil check. Output is discarded unless
+// the tests run with -v.
+func TestMain(m *testing.M) {
+	flag.Parse()
+	out := io.Discard
+	if testing.Verbose() {
+		out = os.Stderr
+	}
+	logger = slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	os.Exit(m.Run())
+}
+
+// testSettings returns a runtime settings snapshot for handler tests, with
+// the data directory in a fresh temp dir.
+func testSettings(t *testing.T) *runtimeSettings {
+	t.Helper()
+	return &runtimeSettings{
+		AppName:         "test-app",
+		Env:             "development",
+		DBUser:          "test_user",
+		Port:            "8080",
+		DataDir:         t.TempDir(),
+		LogFormat:       "json",
+		MaxWriteBytes:   1 << 20,
+		Features:        featureSet{},
+		InfoEnvPrefixes: []string{"APP_", "DB_"},
+		ReadLimit:       rateLimit{50, 100},
+		WriteLimit:      rateLimit{5, 10},
+	}
+}
+
+// fixedSettings is a settingsSource that always returns cfg.
+func fixedSettings(cfg *runtimeSettings) settingsSource {
+	return func() *runtimeSettings { return cfg }
+}
+
+// useWriteSequence numbers writes from a fresh sequence in dir until the test
+// is over.
+func useWriteSequence(t *testing.T, dir string) {
+	t.Helper()
+	seq, err := openWriteSequence(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := writeSeq
+	writeSeq = seq
+	t.Cleanup(func() { writeSeq = prev })
+}
+
+func TestInfoHandlerReflectsSettings(t *testing.T) {
+	for _, cfg := range []*runtimeSettings{
+		{AppName: "alpha", Env: "staging", DBUser: "alice", Port: "9000"},
+		{AppName: "beta", Env: "production", DBUser: "bob", Port: "9443", PodName: "beta-7d9f", Namespace: "demo",
+			Features: featureSet{featurePlainLogs: true}},
+	} {
+		rec := httptest.NewRecorder()
+		newInfoHandler(fixedSettings(cfg))(rec, httptest.NewRequest(http.MethodGet, "/api/info", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200", cfg.AppName, rec.Code)
+		}
+
+		var info AppInfo
+		if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+			t.Fatal(err)
+		}
+		if info.AppName != cfg.AppName || info.Env != cfg.Env || info.DBUser != cfg.DBUser || info.Port != cfg.Port {
+			t.Errorf("%s: got %+v, want the settings it was built with", cfg.AppName, info)
+		}
+		if info.PodName != cfg.PodName || info.Namespace != cfg.Namespace {
+			t.Errorf("%s: pod metadata %q/%q, want %q/%q", cfg.AppName, info.Namespace, info.PodName, cfg.Namespace, cfg.PodName)
+		}
+		if strings.Join(info.Features, ",") != strings.Join(cfg.Features.names(), ",") {
+			t.Errorf("%s: features %q, want %q", cfg.AppName, info.Features, cfg.Features.names())
+		}
+	}
+}
+
+func TestWriteHandlerUsesSettings(t *testing.T) {
+	cfg := testSettings(t)
+	cfg.AppName = "synthetic-app"
+	cfg.Env = "staging"
+	useWriteSequence(t, cfg.DataDir)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/write", nil)
+	req.Header.Set("Accept", mimeJSON)
+	rec := httptest.NewRecorder()
+	newWriteHandler(fixedSettings(cfg))(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	var result WriteResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.LogDir != cfg.DataDir {
+		t.Errorf("log_dir = %q, want %q", result.LogDir, cfg.DataDir)
+	}
+	content, err := os.ReadFile(filepath.Join(cfg.DataDir, result.Filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"synthetic-app", "staging"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("log file does not mention %q", want)
+		}
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	before := atomic.LoadInt64(&panicCount)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "ok") })
+	srv := httptest.NewServer(recoverMiddleware(mux))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/panic")
+	if err != nil {
+		t.Fatalf("panicking handler dropped the connection: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	if !json.Valid(body) || strings.Contains(string(body), "boom") {
+		t.Errorf("body = %s, want a JSON error without the panic value", body)
+	}
+	if got := atomic.LoadInt64(&panicCount) - before; got != 1 {
+		t.Errorf("panicCount went up by %d, want 1", got)
+	}
+
+	// The server keeps serving after a panic
+	resp, err = http.Get(srv.URL + "/ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status after panic = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestRecoverMiddlewareRepanicsAbortHandler(t *testing.T) {
+	before := atomic.LoadInt64(&panicCount)
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", rec)
+		}
+		if atomic.LoadInt64(&panicCount) != before {
+			t.Error("http.ErrAbortHandler counted as a panic")
+		}
+	}()
+	h := recoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { panic(http.ErrAbortHandler) }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"strconv"
+)
+
+// pprofEnabled reports whether DEBUG_PPROF_ENABLED (or the older
+// ENABLE_PPROF) turns on the profiling server.
+func pprofEnabled() bool {
+	raw := getEnvOrDefault("DEBUG_PPROF_ENABLED", "")
+	if raw == "" {
+		raw = getEnvOrDefault("ENABLE_PPROF", "false")
+	}
+	enabled, _ := strconv.ParseBool(raw)
+	return enabled
+}
+
+// debugPort is where the profiling server listens (DEBUG_PORT, default 6060).
+func debugPort() string {
+	return getEnvOrDefault("DEBUG_PORT", "6060")
+}
+
+// validateDebugConfig checks that an enabled profiling server gets a port of
+// its own.
+func validateDebugConfig(port string) []string {
+	if !pprofEnabled() {
+		return nil
+	}
+	debug := debugPort()
+	if err := validatePort(debug); err != nil {
+		return []string{fmt.Sprintf("DEBUG_PORT: %v", err)}
+	}
+	for _, other := range []string{port, os.Getenv("ADMIN_PORT"), os.Getenv("TLS_REDIRECT_PORT")} {
+		if debug == other {
+			return []string{fmt.Sprintf("DEBUG_PORT %s must differ from the main, admin and redirect ports", debug)}
+		}
+	}
+	return nil
+}
+
+// debugRoutes is everything the debug server serves: the profiling handlers
+// and the stats reset, which is too disruptive for the main or admin port.
+func debugRoutes(auth func(http.Handler) http.Handler) []route {
+	return append(pprofRoutes(auth),
+		route{"/api/stats/reset", auth(http.HandlerFunc(statsResetHandler)), "POST /api/stats/reset - Zero the request and write counters", routePublic})
+}
+
+// pprofRoutes exposes the net/http/pprof handlers under /debug/pprof/ for
+// profiling a misbehaving pod in place. They are only ever served by the
+// separate debug server, never on the main or admin port, and go through
+// auth when it is configured. The debug server skips REQUEST_TIMEOUT, and
+// the profile and trace handlers extend HTTP_WRITE_TIMEOUT by the duration
+// asked for, so ?seconds= can be as long as needed.
+func pprofRoutes(auth func(http.Handler) http.Handler) []route {
+	return []route{
+		{"/debug/pprof/", auth(http.HandlerFunc(pprof.Index)), "GET  /debug/pprof/   - Profiling index", routePublic},
+		{"/debug/pprof/cmdline", auth(http.HandlerFunc(pprof.Cmdline)), "GET  /debug/pprof/cmdline", routePublic},
+		{"/debug/pprof/profile", auth(http.HandlerFunc(pprof.Profile)), "GET  /debug/pprof/profile - CPU profile", routePublic},
+		{"/debug/pprof/symbol", auth(http.HandlerFunc(pprof.Symbol)), "GET  /debug/pprof/symbol", routePublic},
+		{"/debug/pprof/trace", auth(http.HandlerFunc(pprof.Trace)), "GET  /debug/pprof/trace - Execution trace", routePublic},
+	}
+}
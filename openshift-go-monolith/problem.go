@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Problem is an RFC 7807 problem details body. Feature is an extension
+// member naming the feature flag that caused the error, if any.
+type Problem struct {
+	Type    string `json:"type"`
+	Title   string `json:"title"`
+	Status  int    `json:"status"`
+	Detail  string `json:"detail,omitempty"`
+	Feature string `json:"feature,omitempty"`
+}
+
+// writeError answers status with an application/problem+json body. The type
+// is always about:blank, so title should be a short summary that stays the
+// same for every occurrence, with the specifics in detail.
+func writeError(w http.ResponseWriter, status int, title, detail string) {
+	writeProblem(w, Problem{Title: title, Status: status, Detail: detail})
+}
+
+// writeProblem is writeError for bodies that carry extension members.
+func writeProblem(w http.ResponseWriter, p Problem) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Prometheus mirrors of the counters reported by /api/stats. They are only
+// ever touched through recordRequest/recordWrite so the two sources stay in
+// lockstep. Everything is registered on the default registry, which already
+// carries the standard Go runtime and process collectors.
+var (
+	httpRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "app_requests_total",
+			Help: "Total number of HTTP requests handled, by matched route and method.",
+		},
+		[]string{"path", "method"},
+	)
+
+	writeOperationsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "app_write_operations_total",
+			Help: "Total number of volume write operations.",
+		},
+	)
+
+	goroutinesGauge = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "app_goroutines",
+			Help: "Number of goroutines currently running.",
+		},
+		func() float64 { return float64(runtime.NumGoroutine()) },
+	)
+
+	heapAllocGauge = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "app_heap_alloc_bytes",
+			Help: "Bytes of allocated heap objects.",
+		},
+		func() float64 {
+			var m runtime.MemStats
+			runtime.ReadMemStats(&m)
+			return float64(m.HeapAlloc)
+		},
+	)
+
+	uptimeGauge = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "app_uptime_seconds",
+			Help: "Seconds since the server process started.",
+		},
+		func() float64 { return time.Since(startTime).Seconds() },
+	)
+
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "app_request_duration_seconds",
+			Help:    "HTTP request latency in seconds.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		httpRequestsTotal,
+		writeOperationsTotal,
+		goroutinesGauge,
+		heapAllocGauge,
+		uptimeGauge,
+		requestDuration,
+	)
+}
+
+// recordRequest bumps the in-process request counter and its Prometheus twin.
+// The series is labelled by the mux pattern r matched rather than its path,
+// and unusual methods are folded into OTHER, so clients can't mint new
+// series.
+func recordRequest(r *http.Request) {
+	atomic.AddInt64(&requestCount, 1)
+	method := r.Method
+	if !statsMethods[method] {
+		method = "OTHER"
+	}
+	httpRequestsTotal.WithLabelValues(patternRoute(r.Pattern), method).Inc()
+}
+
+// recordWrite bumps the in-process write counter and its Prometheus twin.
+func recordWrite() {
+	atomic.AddInt64(&writeCount, 1)
+	writeOperationsTotal.Inc()
+}
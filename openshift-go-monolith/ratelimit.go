@@ -0,0 +1,110 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// limiterIdleTTL is how long an IP may go without requests before its
+	// bucket is dropped.
+	limiterIdleTTL = 5 * time.Minute
+	// limiterSweepInterval is how often idle buckets are looked for.
+	limiterSweepInterval = time.Minute
+)
+
+// rateLimit is a token bucket size: RPS tokens a second, up to Burst.
+type rateLimit struct {
+	RPS   int
+	Burst int
+}
+
+type ipLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// ipLimiterStore hands out one token bucket per client IP, sized by
+// whatever limit returns at the time, so a config reload resizes the
+// buckets already handed out too.
+type ipLimiterStore struct {
+	mu       sync.Mutex
+	limiters map[string]*ipLimiter
+	limit    func() rateLimit
+}
+
+func newIPLimiterStore(limit func() rateLimit) *ipLimiterStore {
+	s := &ipLimiterStore{
+		limiters: make(map[string]*ipLimiter),
+		limit:    limit,
+	}
+	go s.evictLoop()
+	return s
+}
+
+func (s *ipLimiterStore) get(ip string) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lim := s.limit()
+	entry, ok := s.limiters[ip]
+	if !ok {
+		entry = &ipLimiter{}
+		s.limiters[ip] = entry
+	}
+	// New limits start every client on a full bucket under the new policy
+	if !ok || entry.limiter.Limit() != rate.Limit(lim.RPS) || entry.limiter.Burst() != lim.Burst {
+		entry.limiter = rate.NewLimiter(rate.Limit(lim.RPS), lim.Burst)
+	}
+	entry.lastSeen = time.Now()
+	return entry.limiter
+}
+
+func (s *ipLimiterStore) evictLoop() {
+	ticker := time.NewTicker(limiterSweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.mu.Lock()
+		for ip, entry := range s.limiters {
+			if time.Since(entry.lastSeen) > limiterIdleTTL {
+				delete(s.limiters, ip)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// rateLimitMiddleware throttles each client IP to the requests per second
+// and burst limit returns, answering 429 with a Retry-After header once the
+// bucket is empty.
+func rateLimitMiddleware(limit func() rateLimit) func(http.Handler) http.Handler {
+	store := newIPLimiterStore(limit)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := clientIP(r)
+			reservation := store.get(ip).Reserve()
+			if delay := reservation.Delay(); !reservation.OK() || delay > 0 {
+				reservation.Cancel()
+
+				retryAfter := 1
+				if reservation.OK() {
+					retryAfter = int(math.Ceil(delay.Seconds()))
+				}
+				requestLogger(r).Warn("🐢 Rate limit exceeded - slow down bestie",
+					"remote_ip", ip, "path", r.URL.Path, "retry_after_sec", retryAfter)
+
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// runtimeSettings is the typed view of the settings that handlers read on
+// every request, so none of them touch the environment directly. A reload
+// builds a fresh value and swaps the pointer, so a request in flight always
+// sees one consistent snapshot. Port and DataDir are fixed at startup and
+// carried over unchanged.
+type runtimeSettings struct {
+	AppName       string
+	Env           string
+	DBUser        string
+	DBAddr        string // host:port checked by readiness; empty skips it
+	Port          string
+	DataDir       string
+	LogFormat     string
+	MaxWriteBytes int64
+	Features      featureSet
+
+	// InfoEnvPrefixes selects the variables /api/info dumps, and
+	// InfoCacheTTL is how long its response is reused.
+	InfoEnvPrefixes []string
+	InfoCacheTTL    time.Duration
+
+	// ReadLimit and WriteLimit are the per-IP budgets for reads and writes.
+	ReadLimit  rateLimit
+	WriteLimit rateLimit
+
+	// Readiness thresholds for /readyz.
+	ReadinessDelay time.Duration
+	MinFreeDiskMB  uint64
+
+	// Pod metadata injected through the OpenShift downward API; empty when
+	// running outside a cluster.
+	PodName   string
+	Namespace string
+	PodIP     string
+	NodeName  string
+}
+
+var settings atomic.Pointer[runtimeSettings]
+
+// settingsSource returns the settings snapshot a handler should use for the
+// request at hand. main passes settings.Load; anything else can pass a fixed
+// snapshot.
+type settingsSource func() *runtimeSettings
+
+func loadRuntimeSettings() *runtimeSettings {
+	features, _ := parseFeatures(getEnvOrDefault("FEATURES", ""))
+	return &runtimeSettings{
+		AppName:         getEnvOrDefault("APP_NAME", "OpenShift Go Monolith"),
+		Env:             getEnvOrDefault("APP_ENV", "development"),
+		DBUser:          getEnvOrDefault("APP_DB_USER", "not_configured"),
+		DBAddr:          getEnvOrDefault("DB_ADDR", ""),
+		Port:            listenPort,
+		DataDir:         dataDir,
+		LogFormat:       getEnvOrDefault("APP_LOG_FORMAT", "json"),
+		MaxWriteBytes:   int64(getIntOrDefault("MAX_WRITE_BYTES", 1<<20)),
+		Features:        features,
+		InfoEnvPrefixes: splitList(getEnvOrDefault("INFO_ENV_PREFIXES", "APP_,DB_")),
+		InfoCacheTTL:    time.Duration(getIntOrDefault("INFO_CACHE_TTL_SEC", 5)) * time.Second,
+		ReadLimit:       rateLimit{getIntOrDefault("RATE_LIMIT_RPS", 50), getIntOrDefault("RATE_LIMIT_BURST", 100)},
+		WriteLimit:      rateLimit{getIntOrDefault("RATE_LIMIT_WRITE_RPS", 5), getIntOrDefault("RATE_LIMIT_WRITE_BURST", 10)},
+		ReadinessDelay:  time.Duration(getIntOrDefault("READINESS_DELAY_SEC", 5)) * time.Second,
+		MinFreeDiskMB:   uint64(getIntOrDefault("MIN_FREE_DISK_MB", 50)),
+		PodName:         getEnvOrDefault("POD_NAME", ""),
+		Namespace:       getEnvOrDefault("POD_NAMESPACE", ""),
+		PodIP:           getEnvOrDefault("POD_IP", ""),
+		NodeName:        getEnvOrDefault("NODE_NAME", ""),
+	}
+}
+
+// watchReloadSignal re-reads envFile every time the process gets SIGHUP.
+// RELOAD_ON_SIGHUP=false turns that off; SIGHUP is then ignored rather than
+// left to kill the process.
+func watchReloadSignal(envFile string) {
+	if enabled, _ := strconv.ParseBool(getEnvOrDefault("RELOAD_ON_SIGHUP", "true")); !enabled {
+		signal.Ignore(syscall.SIGHUP)
+		logger.Info("[CONFIG] 🔕 SIGHUP reload disabled - restart to pick up config changes")
+		return
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		for range hup {
+			reloadEnvFile(envFile)
+		}
+	}()
+}
+
+// reloadEnvFile re-applies envFile and its .env.$APP_ENV companion with the
+// same precedence as startup, unsets keys the files no longer supply, logs
+// which keys changed (secret values shown as ****) and publishes new
+// runtimeSettings.
+//
+// Only what runtimeSettings carries is reloaded. The log level, TRUST_PROXY,
+// the listen address, the data directory and the write sequence file are
+// read once at startup and need a restart to change.
+func reloadEnvFile(envFile string) {
+	logger.Info("[RELOAD] 🔄 SIGHUP received, reloading env file", "file", envFile)
+
+	merged, origin, results := readEnvFiles(envFile)
+	if err := results[0].err; err != nil {
+		logger.Warn("[RELOAD] ⚠️ Could not read env file, keeping current config", "file", envFile, "error", err)
+		return
+	}
+	for _, ef := range results[1:] {
+		if ef.err != nil {
+			logger.Warn("[RELOAD] ⚠️ Env file not found or unreadable", "file", ef.path, "error", ef.err)
+		}
+	}
+
+	before := make(map[string]string, len(merged)+len(envFromFiles))
+	for key := range merged {
+		before[key] = os.Getenv(key)
+	}
+	for key := range envFromFiles {
+		before[key] = os.Getenv(key)
+	}
+
+	// A key dropped from the files goes back to unset, as after a restart
+	for key := range envFromFiles {
+		if _, ok := merged[key]; !ok {
+			os.Unsetenv(key)
+			delete(envFromFiles, key)
+		}
+	}
+	applyEnvFiles(merged, origin, results)
+
+	changed := 0
+	for key, old := range before {
+		after := os.Getenv(key)
+		if old == after {
+			continue
+		}
+		changed++
+		if isSecretKey(key) {
+			logger.Info("[RELOAD] ✏️ Config key changed", "key", key, "before", "****", "after", "****")
+			continue
+		}
+		logger.Info("[RELOAD] ✏️ Config key changed", "key", key, "before", old, "after", after)
+	}
+
+	settings.Store(loadRuntimeSettings())
+	logger.Info("[RELOAD] ✅ Config reloaded", "file", envFile, "changed_keys", changed)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSIGHUPReloadsInfo(t *testing.T) {
+	unsetEnv(t, "APP_ENV", "APP_NAME", "RATE_LIMIT_RPS", "RELOAD_ON_SIGHUP")
+	t.Setenv("INFO_CACHE_TTL_SEC", "60")
+	prev := settings.Load()
+	t.Cleanup(func() { settings.Store(prev) })
+
+	envFile := filepath.Join(t.TempDir(), ".env")
+	writeEnvFile(t, envFile, "APP_NAME=before\nRATE_LIMIT_RPS=50\n")
+	loadEnvFiles(envFile)
+	settings.Store(loadRuntimeSettings())
+
+	watchReloadSignal(envFile)
+	t.Cleanup(func() { signal.Reset(syscall.SIGHUP) })
+
+	info := newInfoHandler(settings.Load)
+	appName := func() string {
+		rec := httptest.NewRecorder()
+		info(rec, httptest.NewRequest(http.MethodGet, "/api/info", nil))
+		var got AppInfo
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		return got.AppName
+	}
+	if got := appName(); got != "before" {
+		t.Fatalf("AppName = %q before SIGHUP, want before", got)
+	}
+
+	writeEnvFile(t, envFile, "APP_NAME=after\nRATE_LIMIT_RPS=100\n")
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	// The reload runs on its own goroutine; the cached /api/info must not
+	// outlive it
+	deadline := time.Now().Add(2 * time.Second)
+	for appName() != "after" {
+		if time.Now().After(deadline) {
+			t.Fatalf("AppName still %q two seconds after SIGHUP", appName())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := settings.Load().ReadLimit.RPS; got != 100 {
+		t.Errorf("ReadLimit.RPS = %d after SIGHUP, want 100", got)
+	}
+}
+
+func TestReloadEnvFileUnsetsRemovedKeys(t *testing.T) {
+	unsetEnv(t, "APP_ENV", "APP_NAME", "ENV_TEST_DROPPED", "ENV_TEST_SYSTEM")
+	t.Setenv("ENV_TEST_SYSTEM", "from-system")
+	prev := settings.Load()
+	t.Cleanup(func() { settings.Store(prev) })
+
+	envFile := filepath.Join(t.TempDir(), ".env")
+	writeEnvFile(t, envFile, "APP_NAME=kept\nENV_TEST_DROPPED=gone-soon\nENV_TEST_SYSTEM=file\n")
+	loadEnvFiles(envFile)
+
+	writeEnvFile(t, envFile, "APP_NAME=kept\n")
+	reloadEnvFile(envFile)
+	if value, ok := os.LookupEnv("ENV_TEST_DROPPED"); ok {
+		t.Errorf("ENV_TEST_DROPPED = %q after it left the file, want it unset", value)
+	}
+	if got := os.Getenv("ENV_TEST_SYSTEM"); got != "from-system" {
+		t.Errorf("ENV_TEST_SYSTEM = %q, want the system value left alone", got)
+	}
+	if got := settings.Load().AppName; got != "kept" {
+		t.Errorf("AppName = %q, want kept", got)
+	}
+}
+
+func TestReloadEnvFileMasksSecretsInLog(t *testing.T) {
+	unsetEnv(t, "APP_ENV", "API_AUTH_PASS")
+	prev, prevLogger := settings.Load(), logger
+	t.Cleanup(func() { settings.Store(prev); logger = prevLogger })
+	var logs bytes.Buffer
+	logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+	envFile := filepath.Join(t.TempDir(), ".env")
+	writeEnvFile(t, envFile, "API_AUTH_PASS=old-password\n")
+	loadEnvFiles(envFile)
+	writeEnvFile(t, envFile, "API_AUTH_PASS=new-password\n")
+	reloadEnvFile(envFile)
+
+	out := logs.String()
+	if !strings.Contains(out, "key=API_AUTH_PASS") {
+		t.Fatalf("reload did not log the changed key:\n%s", out)
+	}
+	if strings.Contains(out, "password") {
+		t.Errorf("reload logged a secret value:\n%s", out)
+	}
+}
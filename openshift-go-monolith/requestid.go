@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// requestIDHeader carries the trace ID in both directions.
+const requestIDHeader = "X-Request-Id"
+
+// requestIDKey is the context key under which the request ID is stored.
+type requestIDKey struct{}
+
+// maxRequestIDLen bounds how much of a client-supplied ID ends up in logs.
+const maxRequestIDLen = 128
+
+// requestIDMiddleware reuses the caller's X-Request-Id, or mints a UUID when
+// it is missing or unusable, stores it in the request context and echoes it back on the
+// response so every log line for the request can be correlated.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// requestIDFromCtx returns the request ID stored by requestIDMiddleware, or
+// "" outside a request.
+func requestIDFromCtx(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestLogger returns logger tagged with the request's ID.
+func requestLogger(r *http.Request) *slog.Logger {
+	if id := requestIDFromCtx(r.Context()); id != "" {
+		return logger.With("request_id", id)
+	}
+	return logger
+}
+
+// validRequestID accepts non-empty IDs of printable ASCII up to
+// maxRequestIDLen bytes.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random RFC 4122 version 4 UUID.
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Response formats respond can render.
+const (
+	formatJSON = "json"
+	formatYAML = "yaml"
+	formatText = "text"
+)
+
+// Media types the API handlers answer with.
+const (
+	mimeJSON = "application/json"
+	mimeYAML = "application/yaml"
+	mimeText = "text/plain"
+)
+
+// mediaTypeAliases maps the other names clients use for YAML.
+var mediaTypeAliases = map[string]string{
+	"application/x-yaml": mimeYAML,
+	"text/yaml":          mimeYAML,
+}
+
+// acceptRange is one entry of an Accept header.
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// specificity ranks an Accept range: exact types beat type/*, which beats */*.
+func (a acceptRange) specificity() int {
+	switch {
+	case a.mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(a.mediaType, "/*"):
+		return 1
+	}
+	return 2
+}
+
+// negotiateContentType picks the supported type the client weights highest,
+// trying Accept entries by q value and then by specificity, with ties going
+// to the earlier entry. Wildcards match the earliest supported type they
+// cover, and a type listed with q=0 is never picked. With no usable
+// preference it falls back to supported[0].
+func negotiateContentType(r *http.Request, supported []string) string {
+	var ranges []acceptRange
+	refused := make(map[string]bool)
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+		if alias, ok := mediaTypeAliases[mediaType]; ok {
+			mediaType = alias
+		}
+		if mediaType == "" {
+			continue
+		}
+		q := acceptQuality(params)
+		if q <= 0 {
+			refused[mediaType] = true
+			continue
+		}
+		ranges = append(ranges, acceptRange{mediaType, q})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+
+	for _, ar := range ranges {
+		for _, candidate := range supported {
+			if !refused[candidate] && mediaTypeMatches(ar.mediaType, candidate) {
+				return candidate
+			}
+		}
+	}
+	return supported[0]
+}
+
+// acceptQuality returns the q weight among an Accept entry's parameters,
+// 1 when there is none or it doesn't parse.
+func acceptQuality(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if !strings.EqualFold(key, "q") {
+			continue
+		}
+		q, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 1
+		}
+		return q
+	}
+	return 1
+}
+
+// mediaTypeMatches reports whether the Accept range pattern covers mediaType.
+func mediaTypeMatches(pattern, mediaType string) bool {
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(pattern, "/*")
+	return ok && strings.HasPrefix(mediaType, prefix+"/")
+}
+
+// negotiateFormat picks the response format from ?format=, then from the
+// Accept header, defaulting to JSON. ok is false for an unknown ?format=
+// value.
+func negotiateFormat(r *http.Request) (format string, ok bool) {
+	switch f := r.URL.Query().Get("format"); f {
+	case "":
+	case formatJSON, formatYAML, formatText:
+		return f, true
+	default:
+		return "", false
+	}
+
+	switch negotiateContentType(r, []string{mimeJSON, mimeYAML, mimeText}) {
+	case mimeYAML:
+		return formatYAML, true
+	case mimeText:
+		return formatText, true
+	}
+	return formatJSON, true
+}
+
+// respond writes data as JSON, YAML or a plain-text table, whichever the
+// client asked for. All three use the JSON field names. The returned error
+// is for logging; by then the response may be partly written.
+func respond(w http.ResponseWriter, r *http.Request, data any) error {
+	w.Header().Add("Vary", "Accept")
+	format, ok := negotiateFormat(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Unsupported format", "format must be json, yaml or text")
+		return nil
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to encode response", err.Error())
+		return err
+	}
+	if format == formatJSON {
+		w.Header().Set("Content-Type", mimeJSON)
+		_, err = w.Write(append(body, '\n'))
+		return err
+	}
+
+	// JSON is valid YAML, so decoding it into a node keeps the field order
+	var doc yaml.Node
+	if err := yaml.Unmarshal(body, &doc); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to encode response", err.Error())
+		return err
+	}
+	if format == formatYAML {
+		clearNodeStyle(&doc)
+		out, err := yaml.Marshal(&doc)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to encode response", err.Error())
+			return err
+		}
+		w.Header().Set("Content-Type", mimeYAML)
+		_, err = w.Write(out)
+		return err
+	}
+
+	w.Header().Set("Content-Type", mimeText+"; charset=utf-8")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	writeTextRows(tw, "", doc.Content[0])
+	return tw.Flush()
+}
+
+// clearNodeStyle drops the flow style the JSON input left on every node so
+// the YAML comes out in block style.
+func clearNodeStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, child := range n.Content {
+		clearNodeStyle(child)
+	}
+}
+
+// writeTextRows prints one "key<TAB>value" row per scalar, with nested keys
+// joined by dots.
+func writeTextRows(tw *tabwriter.Writer, prefix string, n *yaml.Node) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			writeTextRows(tw, key, n.Content[i+1])
+		}
+	case yaml.SequenceNode:
+		for i, item := range n.Content {
+			writeTextRows(tw, fmt.Sprintf("%s.%d", prefix, i), item)
+		}
+	default:
+		fmt.Fprintf(tw, "%s\t%s\n", prefix, n.Value)
+	}
+}
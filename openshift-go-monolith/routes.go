@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Which server a route lives on once ADMIN_PORT splits traffic in two.
+// Without an admin port everything is served on the main port.
+const (
+	routePublic = iota // main port only
+	routeAdmin         // admin port only
+	routeBoth          // both ports, e.g. probes
+)
+
+// route is one entry in the routing table.
+type route struct {
+	pattern string
+	handler http.Handler
+	line    string // shown in the startup route listing
+	scope   int
+}
+
+// appRoutes is the full routing table. Handlers read their settings from
+// current. Writes touch the volume, so they get writeLimit, which is much
+// tighter than readLimit, and must also pass auth. The rate limit runs first
+// so password guessing is throttled too. /admin/shutdown only exists when
+// ADMIN_TOKEN is set.
+func appRoutes(current settingsSource, readLimit, writeLimit, auth func(http.Handler) http.Handler) []route {
+	routes := []route{
+		{"/", newStaticHandler(), "GET  /              - Static files", routePublic},
+		{"/api/info", readLimit(newInfoHandler(current)), "GET  /api/info      - Application info", routePublic},
+		{"/api/write", writeLimit(auth(newWriteHandler(current))), "POST /api/write     - Write volume data", routePublic},
+		{"/api/write/batch", writeLimit(auth(newBatchWriteHandler(current))), "POST /api/write/batch - Write several log files", routePublic},
+		{"/api/logs", readLimit(newListLogsHandler(current)), "GET  /api/logs      - List written log files", routePublic},
+		{"GET /api/logs/{filename}", readLimit(newGetLogHandler(current)), "GET  /api/logs/{f}  - Read one log file", routePublic},
+		{"DELETE /api/logs/{filename}", writeLimit(auth(newDeleteLogHandler(current))), "DELETE /api/logs/{f} - Delete one log file", routePublic},
+		{"GET /api/version", readLimit(http.HandlerFunc(versionHandler)), "GET  /api/version   - Build metadata", routePublic},
+		{"/api/stats", readLimit(newStatsHandler(current)), "GET  /api/stats     - Application statistics", routeAdmin},
+		{"DELETE /api/stats", writeLimit(auth(http.HandlerFunc(routeStatsResetHandler))), "DELETE /api/stats  - Reset counters and per-route statistics", routeAdmin},
+		{"/api/diskusage", readLimit(newDiskUsageHandler(current)), "GET  /api/diskusage - Data volume utilization", routeAdmin},
+		{"/api/config", readLimit(newConfigHandler(current)), "GET  /api/config    - Effective configuration", routeAdmin},
+		{"/api/applogs", readLimit(auth(http.HandlerFunc(appLogsHandler))), "GET  /api/applogs   - Recent application log lines", routeAdmin},
+		{"/api/applogs/stream", readLimit(auth(http.HandlerFunc(appLogsStreamHandler))), "GET  /api/applogs/stream - Live application log", routeAdmin},
+		{"/metrics", promhttp.Handler(), "GET  /metrics       - Prometheus metrics", routeAdmin},
+		{"/health", http.HandlerFunc(healthzHandler), "GET  /health        - Health check (alias of /healthz/live)", routeBoth},
+		{"/livez", http.HandlerFunc(healthzHandler), "GET  /livez         - Liveness check (alias of /healthz/live)", routeBoth},
+		{"/healthz", http.HandlerFunc(healthzHandler), "GET  /healthz       - Liveness probe", routeBoth},
+		{"/healthz/live", http.HandlerFunc(healthzHandler), "GET  /healthz/live  - Liveness probe", routeBoth},
+		{"/readyz", newReadyzHandler(current), "GET  /readyz        - Readiness probe", routeBoth},
+		{"/healthz/ready", newReadyzHandler(current), "GET  /healthz/ready - Readiness probe", routeBoth},
+		{"/healthz/startup", http.HandlerFunc(startupHandler), "GET  /healthz/startup - Startup probe", routeBoth},
+	}
+	if token := os.Getenv("ADMIN_TOKEN"); token != "" {
+		routes = append(routes, route{"/admin/shutdown", writeLimit(newAdminShutdownHandler(token)), "POST /admin/shutdown - Graceful shutdown on request", routeAdmin})
+	}
+	return routes
+}
+
+// buildMux registers the routes that belong on one server and returns the
+// mux with their listing lines. When split is false every route is served;
+// otherwise admin picks the admin or the public half.
+func buildMux(routes []route, split, admin bool) (*http.ServeMux, []string) {
+	mux := http.NewServeMux()
+	var lines []string
+	for _, rt := range routes {
+		if split && (admin && rt.scope == routePublic || !admin && rt.scope == routeAdmin) {
+			continue
+		}
+		mux.Handle(rt.pattern, rt.handler)
+		lines = append(lines, rt.line)
+	}
+	return mux, lines
+}
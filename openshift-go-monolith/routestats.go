@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// routeStats accumulates the latency of one method and route.
+type routeStats struct {
+	requests atomic.Int64
+	totalNs  atomic.Int64
+	minNs    atomic.Int64 // 0 until the first request
+	maxNs    atomic.Int64
+}
+
+// RouteStats is one entry of the routes map in /api/stats.
+type RouteStats struct {
+	Requests int64   `json:"requests"`
+	AvgMs    float64 `json:"avg_ms"`
+	MinMs    float64 `json:"min_ms"`
+	MaxMs    float64 `json:"max_ms"`
+}
+
+// routeLatencies maps "METHOD /route" to its *routeStats.
+var routeLatencies sync.Map
+
+// statsMethods are the methods that get their own routes entry; anything
+// else is folded into OTHER so clients can't grow the map.
+var statsMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true,
+}
+
+// recordRouteLatency adds one request of duration d to the entry for method
+// and route.
+func recordRouteLatency(method, route string, d time.Duration) {
+	if !statsMethods[method] {
+		method = "OTHER"
+	}
+	v, ok := routeLatencies.Load(method + " " + route)
+	if !ok {
+		v, _ = routeLatencies.LoadOrStore(method+" "+route, &routeStats{})
+	}
+	rs := v.(*routeStats)
+
+	ns := d.Nanoseconds()
+	rs.requests.Add(1)
+	rs.totalNs.Add(ns)
+	for cur := rs.minNs.Load(); cur == 0 || ns < cur; cur = rs.minNs.Load() {
+		if rs.minNs.CompareAndSwap(cur, ns) {
+			break
+		}
+	}
+	for cur := rs.maxNs.Load(); ns > cur; cur = rs.maxNs.Load() {
+		if rs.maxNs.CompareAndSwap(cur, ns) {
+			break
+		}
+	}
+}
+
+// routeLatencySnapshot returns every route's figures in milliseconds.
+func routeLatencySnapshot() map[string]RouteStats {
+	out := make(map[string]RouteStats)
+	routeLatencies.Range(func(key, value any) bool {
+		rs := value.(*routeStats)
+		n := rs.requests.Load()
+		if n == 0 {
+			return true
+		}
+		out[key.(string)] = RouteStats{
+			Requests: n,
+			AvgMs:    nsToMs(rs.totalNs.Load() / n),
+			MinMs:    nsToMs(rs.minNs.Load()),
+			MaxMs:    nsToMs(rs.maxNs.Load()),
+		}
+		return true
+	})
+	return out
+}
+
+// nsToMs converts to milliseconds with microsecond precision.
+func nsToMs(ns int64) float64 {
+	return math.Round(float64(ns)/1e3) / 1e3
+}
+
+// routeStatsResetHandler answers DELETE /api/stats by zeroing the counters,
+// as /api/stats/reset does, and dropping the per-route latencies and the
+// by-path and by-status tallies. It returns the routes and the headline
+// counters as they stood.
+func routeStatsResetHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+
+	prev := routeLatencySnapshot()
+	routeLatencies.Clear()
+	traffic.reset()
+	counters := resetCounters()
+	recordRequest(r)
+	log.Warn("🔄 Stats reset - clean slate", "remote_addr", clientIP(r), "routes", len(prev),
+		"total_requests", counters.TotalRequests, "write_operations", counters.WriteOps)
+
+	w.Header().Set("Content-Type", mimeJSON)
+	if err := json.NewEncoder(w).Encode(map[string]any{"routes": prev, "counters": counters}); err != nil {
+		log.Error("😱 Failed to encode route stats JSON", "error", err)
+	}
+}
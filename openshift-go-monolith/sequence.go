@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// sequenceFileName is the file in the data directory that holds the last
+// write sequence number handed out.
+const sequenceFileName = ".sequence"
+
+// writeSequence numbers writes durably, so file numbers keep increasing
+// across restarts where writeCount would start over.
+type writeSequence struct {
+	mu   sync.Mutex
+	path string
+	last int64
+}
+
+// writeSeq numbers the log files written under the data directory.
+var writeSeq *writeSequence
+
+// openWriteSequence picks up the sequence stored in dir. A missing file
+// starts from zero; one that doesn't hold a number is an error rather than
+// a silent restart of the numbering.
+func openWriteSequence(dir string) (*writeSequence, error) {
+	s := &writeSequence{path: filepath.Join(dir, sequenceFileName)}
+	raw, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.last, err = strconv.ParseInt(strings.TrimSpace(string(raw)), 10, 64)
+	if err != nil || s.last < 0 {
+		return nil, fmt.Errorf("%s does not hold a sequence number: %q", s.path, raw)
+	}
+	return s, nil
+}
+
+// next reserves the following sequence number and saves it before handing
+// it out. A write that then fails leaves a gap, never a repeat.
+func (s *writeSequence) next() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.last + 1
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return 0, fmt.Errorf("create log directory: %w", err)
+	}
+	if err := writeFileAtomic(s.path, []byte(strconv.FormatInt(n, 10)+"\n"), 0644); err != nil {
+		return 0, fmt.Errorf("save write sequence: %w", err)
+	}
+	s.last = n
+	return n, nil
+}
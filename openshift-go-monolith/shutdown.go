@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// resolveShutdownTimeout returns how long in-flight requests get to finish
+// once a shutdown starts. SHUTDOWN_TIMEOUT_SEC (whole seconds) takes
+// precedence over SHUTDOWN_TIMEOUT (a Go duration such as "45s").
+func resolveShutdownTimeout() time.Duration {
+	return secondsOrDuration("SHUTDOWN_TIMEOUT_SEC", "SHUTDOWN_TIMEOUT", 15*time.Second)
+}
+
+// resolveDrainDelay returns how long to keep serving after a shutdown signal
+// before the servers stop accepting connections (DRAIN_DELAY, default 5s).
+// Together with the shutdown timeout it must fit inside the pod's
+// terminationGracePeriodSeconds.
+func resolveDrainDelay() time.Duration {
+	return getDurationOrDefault("DRAIN_DELAY", 5*time.Second)
+}
+
+// preShutdownDrain fails readiness and keeps serving for delay, giving the
+// router time to drop this pod from its endpoints before the listeners
+// close. Without it, rollouts race endpoint removal against SIGTERM and
+// clients see connection refused.
+func preShutdownDrain(delay time.Duration, reason string) {
+	draining.Store(true)
+	if delay <= 0 {
+		return
+	}
+	logger.Info("[SHUTDOWN] 🚦 Drain delay started - readiness now failing", "reason", reason, "delay", delay.String())
+	time.Sleep(delay)
+	logger.Info("[SHUTDOWN] 🚦 Drain delay over - closing listeners", "reason", reason)
+}
+
+// draining is set once a shutdown begins. /readyz then fails so the router
+// stops sending traffic, and responses ask clients to close the connection.
+var draining atomic.Bool
+
+// gracefulShutdown stops every server from accepting new connections and
+// waits up to timeout for in-flight handlers to return, logging the
+// remaining drain time every second. It returns context.DeadlineExceeded when
+// the deadline passes with requests still running.
+func gracefulShutdown(servers []*http.Server, reason string, timeout time.Duration) error {
+	draining.Store(true)
+	logger.Info("[SHUTDOWN] 🛑 Draining in-flight requests", "reason", reason, "timeout", timeout.String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		errs := make(chan error, len(servers))
+		for _, server := range servers {
+			go func() { errs <- server.Shutdown(ctx) }()
+		}
+		var first error
+		for range servers {
+			if err := <-errs; err != nil && first == nil {
+				first = err
+			}
+		}
+		done <- first
+	}()
+
+	deadline := time.Now().Add(timeout)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			if errors.Is(err, context.DeadlineExceeded) {
+				logger.Error("[SHUTDOWN] ⌛ drain timeout exceeded - closing remaining connections", "reason", reason)
+				for _, server := range servers {
+					server.Close()
+				}
+				return err
+			}
+			if err != nil {
+				logger.Error("[SHUTDOWN] 💀 Graceful shutdown failed", "reason", reason, "error", err)
+				return err
+			}
+			logger.Info("[SHUTDOWN] 👋 shutdown complete - peace out!", "reason", reason)
+			return nil
+		case <-ticker.C:
+			logger.Info("[SHUTDOWN] ⏳ Still draining", "reason", reason,
+				"remaining", time.Until(deadline).Round(time.Second).String())
+		}
+	}
+}
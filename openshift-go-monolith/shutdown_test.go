@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// startSlowServer serves a handler that blocks until release is closed and
+// reports when a request has arrived on started.
+func startSlowServer(t *testing.T) (srv *http.Server, addr string, started chan struct{}, release chan struct{}) {
+	t.Helper()
+	started, release = make(chan struct{}, 1), make(chan struct{})
+	srv = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		io.WriteString(w, "finished")
+	})}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(ln)
+	t.Cleanup(func() {
+		srv.Close()
+		draining.Store(false)
+	})
+	return srv, ln.Addr().String(), started, release
+}
+
+func TestGracefulShutdownFinishesInFlightRequest(t *testing.T) {
+	srv, addr, started, release := startSlowServer(t)
+
+	type result struct {
+		body string
+		err  error
+	}
+	inflight := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr)
+		if err != nil {
+			inflight <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		inflight <- result{string(body), err}
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- gracefulShutdown([]*http.Server{srv}, "test", 5*time.Second) }()
+
+	// New connections are refused once the listener is closed...
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("server still accepting connections after shutdown started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !draining.Load() {
+		t.Error("draining not set during shutdown")
+	}
+
+	// ...while the request already in flight still gets its answer
+	select {
+	case err := <-shutdown:
+		t.Fatalf("shutdown returned %v before the in-flight request finished", err)
+	default:
+	}
+	close(release)
+
+	if r := <-inflight; r.err != nil || r.body != "finished" {
+		t.Fatalf("in-flight request got %q, %v; want it to finish", r.body, r.err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Fatalf("gracefulShutdown() = %v, want nil", err)
+	}
+}
+
+func TestGracefulShutdownTimeout(t *testing.T) {
+	srv, addr, started, release := startSlowServer(t)
+	defer close(release)
+
+	go http.Get("http://" + addr)
+	<-started
+
+	err := gracefulShutdown([]*http.Server{srv}, "test", 100*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("gracefulShutdown() = %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+)
+
+// socketPerm lets the owner and group (e.g. a reverse proxy sidecar running
+// under the pod's shared group) connect, and nobody else.
+const socketPerm = 0660
+
+// listenUnixSocket listens on a Unix domain socket at path. A socket left
+// behind by a previous run is removed first; any other kind of file at path
+// is an error rather than something to delete.
+func listenUnixSocket(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&fs.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket %s: %w", path, err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, socketPerm); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("chmod socket %s: %w", path, err)
+	}
+	return ln, nil
+}
+
+// removeSocket deletes the socket file at path, ignoring one that is already
+// gone.
+func removeSocket(path string) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger.Warn("[SHUTDOWN] ⚠️ Failed to remove socket file", "socket", path, "error", err)
+	}
+}
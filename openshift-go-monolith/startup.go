@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Startup task states reported by /healthz/startup.
+const (
+	taskPending = "pending"
+	taskOK      = "ok"
+	taskFailed  = "failed"
+)
+
+// startupTask is one piece of warm-up work that must succeed before the
+// startup probe passes.
+type startupTask struct {
+	name string
+	run  func() error
+}
+
+// StartupTaskStatus is how one startup task went.
+type StartupTaskStatus struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// StartupStatus is the body returned by /healthz/startup.
+type StartupStatus struct {
+	Started bool                `json:"started"`
+	Tasks   []StartupTaskStatus `json:"tasks"`
+}
+
+// startupTracker records the progress of the warm-up tasks.
+type startupTracker struct {
+	mu    sync.Mutex
+	tasks []StartupTaskStatus
+}
+
+// startup is the warm-up run that /healthz/startup reports on.
+var startup startupTracker
+
+// startupTasks is the warm-up work for cfg: the data directory must exist
+// and take writes, the volume needs MIN_FREE_DISK_MB free, and the existing
+// log files are listed once so the first /api/logs doesn't pay for a cold
+// directory.
+func startupTasks(cfg *runtimeSettings) []startupTask {
+	return []startupTask{
+		{"data_dir", func() error { return checkDataDirWritable(cfg.DataDir) }},
+		{"disk_space", func() error {
+			freeMB, err := freeDiskMB(cfg.DataDir)
+			if err != nil {
+				return fmt.Errorf("cannot stat data volume: %w", err)
+			}
+			if freeMB < cfg.MinFreeDiskMB {
+				return fmt.Errorf("%d MB free, need %d MB", freeMB, cfg.MinFreeDiskMB)
+			}
+			return nil
+		}},
+		{"log_index", func() error {
+			_, err := listLogFiles(cfg.DataDir)
+			return err
+		}},
+	}
+}
+
+// run works through tasks in order. A failed task is logged and the rest
+// still run, so the probe body shows everything that is wrong at once; the
+// probe then keeps failing until the pod is restarted.
+func (s *startupTracker) run(tasks []startupTask) {
+	s.mu.Lock()
+	s.tasks = make([]StartupTaskStatus, len(tasks))
+	for i, task := range tasks {
+		s.tasks[i] = StartupTaskStatus{Name: task.name, Status: taskPending}
+	}
+	s.mu.Unlock()
+
+	failed := 0
+	for i, task := range tasks {
+		start := time.Now()
+		err := task.run()
+		status := StartupTaskStatus{Name: task.name, Status: taskOK, DurationMs: time.Since(start).Milliseconds()}
+		if err != nil {
+			failed++
+			status.Status, status.Error = taskFailed, err.Error()
+			logger.Error("[INIT] 💥 Startup task failed - startup probe stays red", "task", task.name, "error", err)
+		} else {
+			logger.Debug("[INIT] ✅ Startup task done", "task", task.name, "duration_ms", status.DurationMs)
+		}
+
+		s.mu.Lock()
+		s.tasks[i] = status
+		s.mu.Unlock()
+	}
+
+	if failed == 0 {
+		logger.Info("[INIT] 🏁 Startup tasks complete - warmed up and ready to slay", "tasks", len(tasks))
+	} else {
+		logger.Error("[INIT] 💀 Startup tasks failed", "tasks", len(tasks), "failed", failed)
+	}
+}
+
+// status snapshots the task list. Startup is over once every task is ok.
+func (s *startupTracker) status() StartupStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := StartupStatus{Started: len(s.tasks) > 0, Tasks: append([]StartupTaskStatus{}, s.tasks...)}
+	for _, task := range s.tasks {
+		if task.Status != taskOK {
+			st.Started = false
+		}
+	}
+	return st
+}
+
+// startupHandler is the startup probe: 503 until every startup task has
+// succeeded, then 200 for the life of the process.
+func startupHandler(w http.ResponseWriter, r *http.Request) {
+	recordRequest(r)
+	log := requestLogger(r)
+
+	status := startup.status()
+	code := http.StatusOK
+	if !status.Started {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Error("😱 Failed to encode startup status JSON", "error", err)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Cache-Control values for the static files. index.html must always be
+// revalidated so a deploy is picked up at once; file names carrying a content
+// hash never change and can be cached for good.
+const (
+	cacheIndex  = "no-cache"
+	cacheHashed = "public, max-age=31536000, immutable"
+	cacheAsset  = "public, max-age=3600"
+)
+
+// hashedAssetPattern matches build-tool names such as app.3f2a9c1b.js or
+// main-5d41402abc4b.css.
+var hashedAssetPattern = regexp.MustCompile(`[.-][0-9a-fA-F]{8,}\.[A-Za-z0-9]+$`)
+
+// newStaticHandler serves the bundled UI from staticDir with cache headers
+// suited to each file. Unknown paths that look like client-side routes get
+// index.html so a single-page front end can handle them; API paths and
+// missing files with an extension still 404. Over HTTP/2, a request for the
+// index page also pushes the paths listed in PUSH_RESOURCES (comma-separated,
+// e.g. /app.css,/app.js) so the browser has them before it has parsed the
+// HTML.
+func newStaticHandler() http.Handler {
+	root := http.Dir(staticDir())
+	files := http.FileServer(root)
+
+	var resources []string
+	for _, target := range splitList(getEnvOrDefault("PUSH_RESOURCES", "")) {
+		if !strings.HasPrefix(target, "/") {
+			logger.Warn("[CONFIG] ⚠️ PUSH_RESOURCES entry must be an absolute path, skipping", "resource", target)
+			continue
+		}
+		resources = append(resources, target)
+	}
+	if len(resources) > 0 {
+		logger.Info("[CONFIG] 📦 HTTP/2 push enabled for the index page", "resources", resources)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean("/" + r.URL.Path)
+		index := name == "/" || name == "/index.html"
+
+		if !index {
+			isDir, found := staticFileInfo(root, name)
+			switch {
+			case found && isDir:
+				w.Header().Set("Cache-Control", cacheIndex)
+			case found:
+				w.Header().Set("Cache-Control", staticCacheControl(name))
+			case isClientRoute(r, name):
+				requestLogger(r).Debug("🧭 Unknown path - handing it to the front end router", "path", r.URL.Path)
+				r = r.Clone(r.Context())
+				r.URL.Path = "/"
+				index = true
+			}
+		}
+
+		if index {
+			w.Header().Set("Cache-Control", cacheIndex)
+			if r.Method == http.MethodGet && len(resources) > 0 {
+				pushResources(w, r, resources)
+			}
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// staticFileInfo reports whether name exists under root and is a directory.
+func staticFileInfo(root http.FileSystem, name string) (isDir, found bool) {
+	f, err := root.Open(name)
+	if err != nil {
+		return false, false
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return false, false
+	}
+	return info.IsDir(), true
+}
+
+// staticCacheControl picks the Cache-Control header for an existing file.
+func staticCacheControl(name string) string {
+	switch {
+	case path.Base(name) == "index.html":
+		return cacheIndex
+	case hashedAssetPattern.MatchString(name):
+		return cacheHashed
+	default:
+		return cacheAsset
+	}
+}
+
+// isClientRoute reports whether a missing path should fall back to
+// index.html: a page load (GET or HEAD) outside /api/ whose last segment has
+// no file extension.
+func isClientRoute(r *http.Request, name string) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if name == "/api" || strings.HasPrefix(name, "/api/") {
+		return false
+	}
+	return path.Ext(name) == ""
+}
+
+// pushResources starts a server push for each target. Clients on HTTP/1.x,
+// or that turned push off, are skipped quietly.
+func pushResources(w http.ResponseWriter, r *http.Request, targets []string) {
+	pusher, ok := w.(http.Pusher)
+	if !ok {
+		return
+	}
+
+	log := requestLogger(r)
+	// Pushed responses get the same encoding the page itself would
+	opts := &http.PushOptions{Header: http.Header{"Accept-Encoding": r.Header.Values("Accept-Encoding")}}
+	for _, target := range targets {
+		err := pusher.Push(target, opts)
+		if errors.Is(err, http.ErrNotSupported) {
+			return
+		}
+		if err != nil {
+			log.Warn("⚠️ HTTP/2 push failed", "resource", target, "error", err)
+			continue
+		}
+		log.Debug("📦 Pushed resource - delivered before you even asked", "resource", target)
+	}
+}
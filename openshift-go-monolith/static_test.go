@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+func TestIndexPushesResourcesOverTLS(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.html": "<html><link rel=stylesheet href=/app.css><script src=/app.js></script></html>",
+		"app.css":    "body { color: teal }",
+		"app.js":     "console.log('pushed')",
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("STATIC_DIR", dir)
+	t.Setenv("PUSH_RESOURCES", "/app.css,/app.js")
+
+	srv := httptest.NewUnstartedServer(newStaticHandler())
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	// net/http's client never accepts pushes, so speak HTTP/2 by hand
+	conn, err := tls.Dial("tcp", srv.Listener.Addr().String(), &tls.Config{
+		InsecureSkipVerify: true,
+		NextProtos:         []string{http2.NextProtoTLS},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if proto := conn.ConnectionState().NegotiatedProtocol; proto != http2.NextProtoTLS {
+		t.Fatalf("negotiated %q, want h2", proto)
+	}
+
+	if _, err := conn.Write([]byte(http2.ClientPreface)); err != nil {
+		t.Fatal(err)
+	}
+	framer := http2.NewFramer(conn, conn)
+	if err := framer.WriteSettings(http2.Setting{ID: http2.SettingEnablePush, Val: 1}); err != nil {
+		t.Fatal(err)
+	}
+	var block bytes.Buffer
+	enc := hpack.NewEncoder(&block)
+	for _, f := range []hpack.HeaderField{
+		{Name: ":method", Value: "GET"},
+		{Name: ":scheme", Value: "https"},
+		{Name: ":authority", Value: srv.Listener.Addr().String()},
+		{Name: ":path", Value: "/"},
+	} {
+		enc.WriteField(f)
+	}
+	if err := framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID: 1, BlockFragment: block.Bytes(), EndStream: true, EndHeaders: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// One decoder for every header block, since they share the connection's
+	// compression state
+	dec := hpack.NewDecoder(4096, nil)
+	promised := make(map[uint32]string)
+	bodies := make(map[uint32]*bytes.Buffer)
+	open := map[uint32]bool{1: true}
+	for len(open) > 0 {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			t.Fatalf("reading frames (open streams %v): %v", open, err)
+		}
+		id := frame.Header().StreamID
+		switch f := frame.(type) {
+		case *http2.SettingsFrame:
+			if !f.IsAck() {
+				framer.WriteSettingsAck()
+			}
+		case *http2.PushPromiseFrame:
+			fields, err := dec.DecodeFull(f.HeaderBlockFragment())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, hf := range fields {
+				if hf.Name == ":path" {
+					promised[f.PromiseID] = hf.Value
+				}
+			}
+			open[f.PromiseID] = true
+		case *http2.HeadersFrame:
+			if _, err := dec.DecodeFull(f.HeaderBlockFragment()); err != nil {
+				t.Fatal(err)
+			}
+			if f.StreamEnded() {
+				delete(open, id)
+			}
+		case *http2.DataFrame:
+			if bodies[id] == nil {
+				bodies[id] = new(bytes.Buffer)
+			}
+			bodies[id].Write(f.Data())
+			if f.StreamEnded() {
+				delete(open, id)
+			}
+		case *http2.RSTStreamFrame:
+			t.Fatalf("stream %d reset: %v", id, f.ErrCode)
+		case *http2.GoAwayFrame:
+			t.Fatalf("server sent GOAWAY: %v", f.ErrCode)
+		}
+	}
+
+	if got := bodies[1].String(); got != files["index.html"] {
+		t.Errorf("index body = %q, want %q", got, files["index.html"])
+	}
+	if len(promised) != 2 {
+		t.Fatalf("got %d push promises %v, want /app.css and /app.js", len(promised), promised)
+	}
+	for stream, target := range promised {
+		want := files[filepath.Base(target)]
+		if bodies[stream] == nil || bodies[stream].String() != want {
+			t.Errorf("pushed %s arrived as %q, want %q", target, bodies[stream], want)
+		}
+	}
+}
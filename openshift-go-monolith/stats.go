@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// statsStart is when the counters last started from zero, if that was after
+// process start; see statsSince.
+var statsStart atomic.Pointer[time.Time]
+
+// statsSince is when counting began: the last /api/stats/reset, or process
+// start. Readiness and the Prometheus uptime keep using startTime.
+func statsSince() time.Time {
+	if t := statsStart.Load(); t != nil {
+		return *t
+	}
+	return startTime
+}
+
+// StatsReset reports the counters as they stood before a reset.
+type StatsReset struct {
+	TotalRequests int64     `json:"total_requests"`
+	WriteOps      int64     `json:"write_operations"`
+	ErrorRate     float64   `json:"error_rate"`
+	Since         time.Time `json:"since"`
+	Uptime        string    `json:"uptime"`
+	ResetAt       time.Time `json:"reset_at"`
+}
+
+// statsResetHandler zeroes the counters and restarts the stats uptime, e.g.
+// between load test runs, and answers with the values it threw away. It is
+// served on the debug port only.
+func statsResetHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	prev := resetCounters()
+	log.Warn("🔄 Stats reset - fresh start, new era", "remote_addr", clientIP(r),
+		"total_requests", prev.TotalRequests, "write_operations", prev.WriteOps, "uptime", prev.Uptime)
+
+	w.Header().Set("Content-Type", mimeJSON)
+	if err := json.NewEncoder(w).Encode(prev); err != nil {
+		log.Error("😱 Failed to encode stats reset JSON", "error", err)
+	}
+}
+
+// resetCounters zeroes every cumulative counter /api/stats reports and
+// restarts the stats uptime, returning the headline figures as they stood.
+// Gauges such as in-flight requests are left alone.
+func resetCounters() StatsReset {
+	now := time.Now()
+	since := statsSince()
+	prev := StatsReset{
+		TotalRequests: atomic.SwapInt64(&requestCount, 0),
+		WriteOps:      atomic.SwapInt64(&writeCount, 0),
+		ErrorRate:     resetErrorRate(),
+		Since:         since,
+		Uptime:        now.Sub(since).Round(time.Second).String(),
+		ResetAt:       now,
+	}
+	for _, counter := range []*int64{&writeOpCount, &deleteCount, &filesCleanedCount, &panicCount, &oversizedBodies, &inflightRejected} {
+		atomic.StoreInt64(counter, 0)
+	}
+	statsStart.Store(&now)
+	return prev
+}
+
+// resetErrorRate zeroes the response counters behind the error rate and
+// returns the rate they gave.
+func resetErrorRate() float64 {
+	responses := atomic.SwapInt64(&responseCount, 0)
+	failed := atomic.SwapInt64(&errorResponseCount, 0)
+	if responses == 0 {
+		return 0
+	}
+	return math.Round(float64(failed)/float64(responses)*10000) / 10000
+}
+
+// collectStats gathers everything /api/stats reports for the data directory
+// in cfg. log receives the debug line when disk usage can't be read.
+func collectStats(cfg *runtimeSettings, log *slog.Logger) Stats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	uptime := time.Since(statsSince())
+	responses := atomic.LoadInt64(&responseCount)
+	stats := Stats{
+		Uptime:           uptime.Round(time.Second).String(),
+		TotalRequests:    atomic.LoadInt64(&requestCount),
+		WriteOps:         atomic.LoadInt64(&writeCount),
+		DeleteOps:        atomic.LoadInt64(&deleteCount),
+		FilesCleaned:     atomic.LoadInt64(&filesCleanedCount),
+		Panics:           atomic.LoadInt64(&panicCount),
+		GoVersion:        runtime.Version(),
+		NumCPU:           runtime.NumCPU(),
+		NumGoroutines:    runtime.NumGoroutine(),
+		MemoryAllocMB:    m.Alloc / 1024 / 1024,
+		HeapObjects:      m.HeapObjects,
+		NextGCMB:         m.NextGC / 1024 / 1024,
+		NumGC:            m.NumGC,
+		GCPauseTotalMs:   float64(m.PauseTotalNs) / 1e6,
+		OpenFDs:          openFDCount(),
+		ServerTime:       time.Now().Format(time.RFC3339),
+		LogLevel:         strings.ToLower(logLevel.Level().String()),
+		ShuttingDown:     draining.Load(),
+		InFlight:         atomic.LoadInt64(&inflightRequests),
+		InFlightRejected: atomic.LoadInt64(&inflightRejected),
+		OversizedBodies:  atomic.LoadInt64(&oversizedBodies),
+		ErrorResponses:   atomic.LoadInt64(&errorResponseCount),
+		Build:            currentVersionInfo(),
+	}
+	// Rates stay 0 until there is something to divide by
+	if uptime >= time.Second {
+		stats.RequestsPerSec = math.Round(float64(stats.TotalRequests)/uptime.Seconds()*100) / 100
+	}
+	if responses > 0 {
+		stats.ErrorRate = math.Round(float64(stats.ErrorResponses)/float64(responses)*10000) / 10000
+	}
+	stats.RequestsByPath, stats.RequestsByStatus = traffic.snapshot()
+	stats.Routes = routeLatencySnapshot()
+	if usage, err := diskUsage(cfg.DataDir); err == nil {
+		stats.DiskUsage = &usage
+	} else {
+		log.Debug("💽 Disk usage unavailable for stats", "dir", cfg.DataDir, "error", err)
+	}
+	return stats
+}
+
+// openFDCount counts the process's open file descriptors via /proc, so it
+// is Linux only; elsewhere it returns -1.
+func openFDCount() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	// One of the entries is the descriptor ReadDir itself had open
+	return len(entries) - 1
+}
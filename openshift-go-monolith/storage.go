@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// writeLogFileCtx creates dir if needed and atomically writes data to path,
+// giving up between steps once ctx is cancelled. An abandoned write leaves
+// nothing behind. The I/O is traced as a write_log_file span.
+func writeLogFileCtx(ctx context.Context, dir, path string, data []byte) (err error) {
+	ctx, span := tracer.Start(ctx, "write_log_file", trace.WithAttributes(
+		attribute.String("file.path", path),
+		attribute.Int("file.size_bytes", len(data)),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create log directory: %w", err)
+	}
+	return writeFileAtomicCtx(ctx, path, data, 0644)
+}
+
+// writeFileAtomic writes data to a temp file next to path, syncs it to disk
+// and renames it into place, so readers see either the complete file or
+// nothing, even if the pod is killed mid-write. The rename is only atomic on
+// the same filesystem, which is why the temp file lives in the target
+// directory. A kill before the rename leaves the temp file behind for
+// removeOrphanedTempFiles.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	return writeFileAtomicCtx(context.Background(), path, data, perm)
+}
+
+// writeFileAtomicCtx is writeFileAtomic that also stops, discarding the temp
+// file, if ctx is cancelled before the rename.
+func writeFileAtomicCtx(ctx context.Context, path string, data []byte, perm os.FileMode) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	dir, base := filepath.Split(path)
+	tmp, err := os.CreateTemp(dir, "."+base+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op once the rename has happened
+
+	if err := ctx.Err(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	// Without the sync a crash after the rename can leave an empty file
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return os.Rename(tmpName, path)
+}
+
+// removeOrphanedTempFiles deletes the temp files writeFileAtomic left in dir
+// when the process died before renaming them. Nothing else writes there
+// while it runs, so every match is an orphan.
+func removeOrphanedTempFiles(dir string) {
+	matches, err := filepath.Glob(filepath.Join(dir, ".*.tmp"))
+	if err != nil {
+		logger.Warn("[INIT] ⚠️ Could not look for orphaned temp files", "dir", dir, "error", err)
+		return
+	}
+	for _, name := range matches {
+		var size int64
+		if info, err := os.Stat(name); err == nil {
+			size = info.Size()
+		}
+		if err := os.Remove(name); err != nil {
+			logger.Warn("[INIT] ⚠️ Failed to remove orphaned temp file", "file", name, "error", err)
+			continue
+		}
+		logger.Warn("[INIT] 🧟 Removed orphaned temp file from an interrupted write", "file", name, "size_bytes", size)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// serverTimeouts bound how long one connection may hold the server, so a
+// slow-loris client trickling headers or a body can't pin connections.
+type serverTimeouts struct {
+	read       time.Duration
+	readHeader time.Duration
+	write      time.Duration
+	idle       time.Duration
+}
+
+// loadServerTimeouts reads HTTP_READ_TIMEOUT, HTTP_READ_HEADER_TIMEOUT,
+// HTTP_WRITE_TIMEOUT and HTTP_IDLE_TIMEOUT as Go durations. 0 disables one.
+func loadServerTimeouts() serverTimeouts {
+	t := serverTimeouts{
+		read:       getDurationOrDefault("HTTP_READ_TIMEOUT", 15*time.Second),
+		readHeader: getDurationOrDefault("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		write:      getDurationOrDefault("HTTP_WRITE_TIMEOUT", 30*time.Second),
+		idle:       getDurationOrDefault("HTTP_IDLE_TIMEOUT", 120*time.Second),
+	}
+	logger.Info("[CONFIG] ⏱️ HTTP server timeouts",
+		"read", t.read.String(), "read_header", t.readHeader.String(),
+		"write", t.write.String(), "idle", t.idle.String())
+	return t
+}
+
+func (t serverTimeouts) apply(server *http.Server) {
+	server.ReadTimeout = t.read
+	server.ReadHeaderTimeout = t.readHeader
+	server.WriteTimeout = t.write
+	server.IdleTimeout = t.idle
+}
+
+// serverConnOptions cap header size and control connection reuse. They are
+// fixed at startup and reported by /api/config.
+type serverConnOptions struct {
+	maxHeaderBytes int
+	keepAlives     bool
+}
+
+// connOptions holds what loadServerConnOptions resolved.
+var connOptions = serverConnOptions{maxHeaderBytes: http.DefaultMaxHeaderBytes, keepAlives: true}
+
+// loadServerConnOptions reads MAX_HEADER_BYTES (default 1MiB, Go's own
+// default) and DISABLE_KEEPALIVES, which closes every connection after one
+// request when chasing connection reuse problems.
+func loadServerConnOptions() serverConnOptions {
+	o := serverConnOptions{
+		maxHeaderBytes: getIntOrDefault("MAX_HEADER_BYTES", http.DefaultMaxHeaderBytes),
+		keepAlives:     true,
+	}
+	if disabled, _ := strconv.ParseBool(getEnvOrDefault("DISABLE_KEEPALIVES", "false")); disabled {
+		o.keepAlives = false
+		logger.Warn("[CONFIG] 🔌 Keep-alives disabled - one request per connection")
+	}
+	logger.Info("[CONFIG] 📏 HTTP connection options",
+		"max_header_bytes", o.maxHeaderBytes, "keep_alives", o.keepAlives)
+	return o
+}
+
+func (o serverConnOptions) apply(server *http.Server) {
+	server.MaxHeaderBytes = o.maxHeaderBytes
+	server.SetKeepAlivesEnabled(o.keepAlives)
+}
+
+// timeoutMiddleware gives each handler d to finish before the client gets a
+// JSON 503 and the request context is cancelled, so a hung handler can't
+// hold its connection. 0 disables it. Responses are buffered until the
+// handler returns.
+func timeoutMiddleware(d time.Duration) func(http.Handler) http.Handler {
+	if d <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return func(next http.Handler) http.Handler {
+		timeout := http.TimeoutHandler(next, d, `{"error":"request timeout"}`)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout.ServeHTTP(&timeoutResponseWriter{ResponseWriter: w}, r)
+		})
+	}
+}
+
+// timeoutResponseWriter labels http.TimeoutHandler's bare 503 body as JSON.
+// Handlers' own responses arrive with their headers already set.
+type timeoutResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (tw *timeoutResponseWriter) WriteHeader(code int) {
+	if code == http.StatusServiceUnavailable && tw.Header().Get("Content-Type") == "" {
+		tw.Header().Set("Content-Type", "application/json")
+	}
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+// Push passes HTTP/2 server push through to the connection underneath.
+func (tw *timeoutResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := tw.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// requestTimeouts are the per-request deadlines: REQUEST_TIMEOUT_SEC (or the
+// REQUEST_TIMEOUT duration, default 30s) for every route, except that the
+// write routes use REQUEST_TIMEOUT_WRITE_SEC and /api/stats uses
+// REQUEST_TIMEOUT_STATS_SEC when those are set. Log file routes and the
+// app log stream never time out: http.TimeoutHandler buffers the whole
+// response, and those two must stream. The debug server skips timeouts
+// altogether.
+type requestTimeouts struct {
+	def       time.Duration
+	overrides map[string]time.Duration // by mux route
+}
+
+func loadRequestTimeouts() requestTimeouts {
+	def := secondsOrDuration("REQUEST_TIMEOUT_SEC", "REQUEST_TIMEOUT", 30*time.Second)
+	write := secondsOrDuration("REQUEST_TIMEOUT_WRITE_SEC", "", def)
+	stats := secondsOrDuration("REQUEST_TIMEOUT_STATS_SEC", "", def)
+	logger.Info("[CONFIG] ⏱️ Per-request timeouts",
+		"default", def.String(), "write", write.String(), "stats", stats.String())
+
+	return requestTimeouts{def: def, overrides: map[string]time.Duration{
+		"/api/write":       write,
+		"/api/write/batch": write,
+		"/api/stats":       stats,
+
+		"/api/logs/{filename}": 0,
+		"/api/applogs/stream":  0,
+	}}
+}
+
+// middleware applies the timeout for each request's route, which routes
+// resolves to its mux pattern.
+func (t requestTimeouts) middleware(routes *http.ServeMux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		byDuration := map[time.Duration]http.Handler{t.def: timeoutMiddleware(t.def)(next)}
+		for _, d := range t.overrides {
+			if _, ok := byDuration[d]; !ok {
+				byDuration[d] = timeoutMiddleware(d)(next)
+			}
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d, ok := t.overrides[routeFor(routes, r)]
+			if !ok {
+				d = t.def
+			}
+			byDuration[d].ServeHTTP(w, r)
+		})
+	}
+}
+
+// secondsOrDuration reads secKey as whole seconds, then durKey (if any) as a
+// Go duration such as "45s", then falls back to def.
+func secondsOrDuration(secKey, durKey string, def time.Duration) time.Duration {
+	if raw := getEnvOrDefault(secKey, ""); raw != "" {
+		secs, err := strconv.Atoi(raw)
+		if err != nil || secs < 0 {
+			logger.Warn("⚠️ Invalid "+secKey+", using default", "value", raw, "default", def.String())
+			return def
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if durKey == "" {
+		return def
+	}
+	return getDurationOrDefault(durKey, def)
+}
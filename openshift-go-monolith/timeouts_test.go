@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveWith starts srv on a loopback listener and returns its address.
+func serveWith(t *testing.T, srv *http.Server) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+	return ln.Addr().String()
+}
+
+func TestStalledBodyIsDisconnected(t *testing.T) {
+	t.Setenv("HTTP_READ_TIMEOUT", "200ms")
+	t.Setenv("HTTP_READ_HEADER_TIMEOUT", "200ms")
+
+	bodyErr := make(chan error, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		bodyErr <- err
+	})}
+	loadServerTimeouts().apply(srv)
+	addr := serveWith(t, srv)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Promise 100 bytes, send 10 and then go quiet
+	io.WriteString(conn, "POST /api/write HTTP/1.1\r\nHost: test\r\nContent-Length: 100\r\n\r\n0123456789")
+
+	select {
+	case err := <-bodyErr:
+		if err == nil {
+			t.Fatal("handler read the whole body from a stalled client")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler still waiting on the body after the read timeout")
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = io.ReadAll(conn)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatal("server kept the stalled connection open")
+	}
+}
+
+func TestOversizedHeadersGet431(t *testing.T) {
+	t.Setenv("MAX_HEADER_BYTES", "1024")
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})}
+	// net/http allows 4KiB of slack on top of MaxHeaderBytes
+	loadServerConnOptions().apply(srv)
+	url := "http://" + serveWith(t, srv) + "/api/info"
+
+	for _, tt := range []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"small", strings.Repeat("a", 100), http.StatusOK},
+		{"oversized", strings.Repeat("a", 16<<10), http.StatusRequestHeaderFieldsTooLarge},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("X-Padding", tt.header)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+}
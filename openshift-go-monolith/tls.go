@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// certReloadInterval is how often the certificate files are checked for
+// rotation.
+const certReloadInterval = 60 * time.Second
+
+// certReloader serves the key pair from certFile/keyFile through
+// tls.Config.GetCertificate and picks up rotated files (e.g. from
+// cert-manager) without a restart.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reload reads the key pair from disk and swaps it in.
+func (c *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS key pair: %w", err)
+	}
+	modTime := c.latestModTime()
+
+	c.mu.Lock()
+	c.cert = &cert
+	c.modTime = modTime
+	c.mu.Unlock()
+	return nil
+}
+
+// latestModTime returns the newer of the two files' modification times.
+func (c *certReloader) latestModTime() time.Time {
+	var latest time.Time
+	for _, path := range []string{c.certFile, c.keyFile} {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// watch polls the certificate files every interval and reloads them when
+// either one changes. A failed reload keeps serving the previous cert.
+func (c *certReloader) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		c.mu.RLock()
+		current := c.modTime
+		c.mu.RUnlock()
+
+		if !c.latestModTime().After(current) {
+			continue
+		}
+		if err := c.reload(); err != nil {
+			logger.Error("🔐 Failed to reload rotated TLS certificate, keeping the old one", "error", err)
+			continue
+		}
+		logger.Info("🔐 TLS certificate rotated - fresh cert who dis", "cert_file", c.certFile)
+	}
+}
+
+// tlsFiles returns the certificate and key paths from TLS_CERT_FILE and
+// TLS_KEY_FILE, or the shorter TLS_CERT and TLS_KEY.
+func tlsFiles() (certFile, keyFile string) {
+	certFile, keyFile = os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE")
+	if certFile == "" {
+		certFile = os.Getenv("TLS_CERT")
+	}
+	if keyFile == "" {
+		keyFile = os.Getenv("TLS_KEY")
+	}
+	return certFile, keyFile
+}
+
+// validateTLSConfig reports TLS settings that would otherwise only fail at
+// startup: a certificate without its key (or the reverse), and a
+// TLS_REDIRECT_PORT that is invalid, clashes with another port, or has no
+// HTTPS listener to redirect to.
+func validateTLSConfig(port string) []string {
+	var problems []string
+
+	certFile, keyFile := tlsFiles()
+	if (certFile == "") != (keyFile == "") {
+		problems = append(problems, "TLS_CERT_FILE and TLS_KEY_FILE must be set together to enable HTTPS")
+	}
+
+	if redirect := os.Getenv("TLS_REDIRECT_PORT"); redirect != "" {
+		switch err := validatePort(redirect); {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("TLS_REDIRECT_PORT: %v", err))
+		case certFile == "" || keyFile == "":
+			problems = append(problems, "TLS_REDIRECT_PORT requires TLS_CERT_FILE and TLS_KEY_FILE")
+		case redirect == port || redirect == os.Getenv("ADMIN_PORT"):
+			problems = append(problems, fmt.Sprintf("TLS_REDIRECT_PORT %s must differ from the main and admin ports", redirect))
+		}
+	}
+	return problems
+}
+
+// newRedirectHandler answers every request with a 301 to the same host and
+// path on the HTTPS port. The default port 443 is left out of the URL.
+func newRedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		} else {
+			host = strings.Trim(host, "[]")
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// trafficCounters tallies completed requests by matched route and by status
+// class for /api/stats. Routes come from the mux's registered patterns, so
+// the map stays bounded no matter what paths clients send.
+type trafficCounters struct {
+	mu       sync.Mutex
+	byPath   map[string]int64
+	byStatus map[string]int64
+}
+
+var traffic = &trafficCounters{
+	byPath:   make(map[string]int64),
+	byStatus: make(map[string]int64),
+}
+
+func (t *trafficCounters) record(route string, status int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.byPath[route]++
+	t.byStatus[statusClass(status)]++
+}
+
+// reset forgets everything tallied so far.
+func (t *trafficCounters) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	clear(t.byPath)
+	clear(t.byStatus)
+}
+
+// snapshot returns copies of both maps that are safe to encode.
+func (t *trafficCounters) snapshot() (byPath, byStatus map[string]int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return maps.Clone(t.byPath), maps.Clone(t.byStatus)
+}
+
+// statusClass buckets a status code as "2xx", "4xx" and so on.
+func statusClass(status int) string {
+	return string(rune('0'+status/100)) + "xx"
+}
+
+// routeFor reports the path of the mux pattern that serves r, without the
+// method prefix, or "unmatched" when nothing does.
+func routeFor(mux *http.ServeMux, r *http.Request) string {
+	_, pattern := mux.Handler(r)
+	return patternRoute(pattern)
+}
+
+// patternRoute strips the method prefix from a mux pattern, reporting an
+// empty one as "unmatched".
+func patternRoute(pattern string) string {
+	if pattern == "" {
+		return "unmatched"
+	}
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return path
+	}
+	return pattern
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// staticDir is the directory served at / (STATIC_DIR, default ./static).
+func staticDir() string {
+	return getEnvOrDefault("STATIC_DIR", "./static")
+}
+
+// validationReport is the dry-run summary printed by -validate.
+type validationReport struct {
+	Valid      bool        `json:"valid"`
+	Problems   []string    `json:"problems"`
+	Build      VersionInfo `json:"build"`
+	AppEnv     string      `json:"app_env"`
+	ListenAddr string      `json:"listen_addr"`
+	DataDir    string      `json:"data_dir"`
+	EnvFiles   []string    `json:"env_files"`
+	ConfigFile string      `json:"config_file,omitempty"`
+	TLS        bool        `json:"tls"`
+}
+
+// validateOnly reports whether to run the dry-run config check instead of
+// serving, from -validate or VALIDATE_ONLY=true.
+func (f *cliFlags) validateOnly() bool {
+	if f.validate {
+		return true
+	}
+	v, _ := strconv.ParseBool(os.Getenv("VALIDATE_ONLY"))
+	return v
+}
+
+// checkStaticDir makes sure the static file directory is present.
+func checkStaticDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("static directory %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("static directory %s is not a directory", dir)
+	}
+	return nil
+}
+
+// checkTLSFiles loads the configured certificate pair, if any. A half-set
+// pair is reported by validateTLSConfig.
+func checkTLSFiles(certFile, keyFile string) error {
+	if certFile == "" || keyFile == "" {
+		return nil
+	}
+	if _, err := newCertReloader(certFile, keyFile); err != nil {
+		return fmt.Errorf("TLS: %w", err)
+	}
+	return nil
+}
+
+// writeValidationReport prints report as JSON or as a short human summary.
+func writeValidationReport(w io.Writer, report validationReport, asJSON bool) error {
+	if report.Problems == nil {
+		report.Problems = []string{}
+	}
+	if report.EnvFiles == nil {
+		report.EnvFiles = []string{}
+	}
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	if report.Valid {
+		fmt.Fprintln(w, "✅ Configuration is valid - ship it!")
+	} else {
+		fmt.Fprintf(w, "❌ Configuration has %d problem(s):\n", len(report.Problems))
+		for _, p := range report.Problems {
+			fmt.Fprintf(w, "  - %s\n", p)
+		}
+	}
+	fmt.Fprintf(w, "  version:     %s (%s)\n", report.Build.Version, report.Build.GitCommit)
+	fmt.Fprintf(w, "  app_env:     %s\n", report.AppEnv)
+	fmt.Fprintf(w, "  listen_addr: %s\n", report.ListenAddr)
+	fmt.Fprintf(w, "  data_dir:    %s\n", report.DataDir)
+	fmt.Fprintf(w, "  env_files:   %v\n", report.EnvFiles)
+	if report.ConfigFile != "" {
+		fmt.Fprintf(w, "  config_file: %s\n", report.ConfigFile)
+	}
+	fmt.Fprintf(w, "  tls:         %t\n", report.TLS)
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+)
+
+// Build metadata, injected at link time:
+//
+//	go build -ldflags "-X main.Version=$(git describe --tags --always) -X main.GitCommit=$(git rev-parse --short HEAD) -X main.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)" -o app .
+var (
+	Version   = "dev"
+	GitCommit = "unknown"
+	BuildTime = "unknown"
+)
+
+// VersionInfo describes the binary that is running.
+type VersionInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+func currentVersionInfo() VersionInfo {
+	return VersionInfo{Version: Version, GitCommit: GitCommit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+}
+
+// versionHandler serves just the build metadata so CI can check which commit
+// a rollout is actually running.
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	recordRequest(r)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(currentVersionInfo()); err != nil {
+		requestLogger(r).Error("😱 Failed to encode version JSON", "error", err)
+	}
+}